@@ -0,0 +1,211 @@
+// Package batch automatic batching of concurrent single-key reads
+//
+// 自动合并并发的单 key 读取，一个时间窗口内的 Get、HGet、ZGet 合并为一次 MultiGet、MultiHGet、MultiZGet，减少请求往返次数
+package batch
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/seefan/gossdb/v2/client"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+const (
+	kindGet = iota
+	kindHGet
+	kindZGet
+)
+
+// Stats the statistics of the batcher
+//
+// 合并的统计信息
+type Stats struct {
+	//number of the reads
+	//读取的次数
+	Requests int64
+	//number of the batch commands sent
+	//发送的批量命令的次数
+	Batches int64
+}
+
+// Batcher collects the concurrent Get, HGet and ZGet within a short window and sends them as one multi command, goroutine safe.
+// A batch is sent when the window elapses or when it reaches the maximum size. The results are the same as the client functions,
+// a missing key returns the zero value without error.
+//
+// 合并器，协程安全。收集一个时间窗口内并发的 Get、HGet、ZGet 请求，作为一个批量命令发送，窗口到期或达到最大数量时发送。
+// 结果与 client 的函数一致，key 不存在时返回零值且不报错。
+type Batcher struct {
+	pool *pool.Connectors
+	//收集请求的时间窗口
+	window time.Duration
+	//一批最多的 key 数量
+	maxBatch int
+	lock     sync.Mutex
+	//正在收集的批次，按类型和名称分组
+	groups   map[groupKey]*group
+	requests int64
+	batches  int64
+}
+
+// 批次的分组
+type groupKey struct {
+	kind int
+	name string
+}
+
+// 一个批次
+type group struct {
+	key groupKey
+	//去重后的 key
+	keys []string
+	seen map[string]struct{}
+	//是否已经发送
+	sent bool
+	//发送完成后关闭
+	done chan struct{}
+	vals map[string]client.Value
+	zval map[string]int64
+	err  error
+}
+
+// New create a batcher
+//
+//	@param p connection pool
+//	@param window time to collect the reads, 1ms if not positive
+//	@param maxBatch maximum number of the keys in a batch, 100 if not positive
+//	@return *Batcher
+//
+// 创建一个合并器
+func New(p *pool.Connectors, window time.Duration, maxBatch int) *Batcher {
+	if window <= 0 {
+		window = time.Millisecond
+	}
+	if maxBatch < 1 {
+		maxBatch = 100
+	}
+	return &Batcher{
+		pool:     p,
+		window:   window,
+		maxBatch: maxBatch,
+		groups:   make(map[groupKey]*group),
+	}
+}
+
+// Get returns the value of the key, see client.Client.Get
+//
+// 获取指定 key 的值，与其它并发的 Get 合并为 MultiGet
+func (b *Batcher) Get(key string) (client.Value, error) {
+	g := b.add(groupKey{kind: kindGet}, key)
+	<-g.done
+	if g.err != nil {
+		return "", g.err
+	}
+	return g.vals[key], nil
+}
+
+// HGet returns the value of the key in the hashmap, see client.Client.HGet
+//
+// 获取 hashmap 中指定 key 的值，与同一 hashmap 的其它并发的 HGet 合并为 MultiHGet
+func (b *Batcher) HGet(setName, key string) (client.Value, error) {
+	g := b.add(groupKey{kind: kindHGet, name: setName}, key)
+	<-g.done
+	if g.err != nil {
+		return "", g.err
+	}
+	return g.vals[key], nil
+}
+
+// ZGet returns the score of the key in the zset, see client.Client.ZGet
+//
+// 获取 zset 中指定 key 的权重，与同一 zset 的其它并发的 ZGet 合并为 MultiZGet
+func (b *Batcher) ZGet(setName, key string) (int64, error) {
+	g := b.add(groupKey{kind: kindZGet, name: setName}, key)
+	<-g.done
+	if g.err != nil {
+		return 0, g.err
+	}
+	return g.zval[key], nil
+}
+
+// Stats returns the statistics, Requests/Batches is the average batch size
+//
+// 返回统计信息，Requests/Batches 为平均每批的请求数
+func (b *Batcher) Stats() Stats {
+	return Stats{
+		Requests: atomic.LoadInt64(&b.requests),
+		Batches:  atomic.LoadInt64(&b.batches),
+	}
+}
+
+// 把 key 加入正在收集的批次，第一个 key 启动窗口计时，达到最大数量时立即发送
+func (b *Batcher) add(k groupKey, key string) *group {
+	atomic.AddInt64(&b.requests, 1)
+	b.lock.Lock()
+	g, ok := b.groups[k]
+	if !ok {
+		g = &group{
+			key:  k,
+			seen: make(map[string]struct{}),
+			done: make(chan struct{}),
+		}
+		b.groups[k] = g
+		time.AfterFunc(b.window, func() {
+			b.flush(g)
+		})
+	}
+	if _, ok = g.seen[key]; !ok {
+		g.seen[key] = struct{}{}
+		g.keys = append(g.keys, key)
+	}
+	full := len(g.keys) >= b.maxBatch
+	if full {
+		b.detach(g)
+	}
+	b.lock.Unlock()
+	if full {
+		go b.send(g)
+	}
+	return g
+}
+
+// 从收集中移除批次，返回是否需要由调用者发送，需要持有锁
+func (b *Batcher) detach(g *group) bool {
+	if g.sent {
+		return false
+	}
+	g.sent = true
+	if b.groups[g.key] == g {
+		delete(b.groups, g.key)
+	}
+	return true
+}
+
+// 窗口到期，发送还没有发送的批次
+func (b *Batcher) flush(g *group) {
+	b.lock.Lock()
+	send := b.detach(g)
+	b.lock.Unlock()
+	if send {
+		b.send(g)
+	}
+}
+
+// 发送批量命令并通知等待者
+func (b *Batcher) send(g *group) {
+	atomic.AddInt64(&b.batches, 1)
+	defer close(g.done)
+	g.err = b.pool.With(context.Background(), func(c *client.Client) (err error) {
+		switch g.key.kind {
+		case kindGet:
+			g.vals, err = c.MultiGet(g.keys...)
+		case kindHGet:
+			g.vals, err = c.MultiHGet(g.key.name, g.keys...)
+		case kindZGet:
+			g.zval, err = c.MultiZGet(g.key.name, g.keys...)
+		}
+		return err
+	})
+}
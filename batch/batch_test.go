@@ -0,0 +1,71 @@
+package batch
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+func TestBatcher(t *testing.T) {
+	p := pool.NewConnectors(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	c, err := p.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AutoClose = false
+	for i := 0; i < 50; i++ {
+		k := fmt.Sprintf("batch:%d", i)
+		if err = c.Set(k, k); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.HSet("batch:h", k, k); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.ZSet("batch:z", k, int64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Close()
+
+	b := New(p, 5*time.Millisecond, 20)
+	var wg sync.WaitGroup
+	for i := 0; i < 60; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			k := fmt.Sprintf("batch:%d", i)
+			v, err := b.Get(k)
+			//不存在的 key 返回空值
+			if i >= 50 {
+				k = ""
+			}
+			if err != nil || v.String() != k {
+				t.Errorf("get %d: %q %v", i, v, err)
+			}
+			if v, err = b.HGet("batch:h", k); err != nil || v.String() != k {
+				t.Errorf("hget %d: %q %v", i, v, err)
+			}
+			if i < 50 {
+				if s, err := b.ZGet("batch:z", k); err != nil || s != int64(i) {
+					t.Errorf("zget %d: %d %v", i, s, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	s := b.Stats()
+	if s.Requests != 170 || s.Batches >= s.Requests/5 {
+		t.Errorf("not batched: %+v", s)
+	}
+}
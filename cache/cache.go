@@ -0,0 +1,243 @@
+// Package cache read-through cache backed by ssdb
+//
+// 基于 ssdb 的读穿透缓存，支持进程内合并加载、概率提前过期（XFetch）和未命中缓存
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/seefan/goerr"
+	"github.com/seefan/gossdb/v2/client"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+// ErrNotFound returned by the loader when the value does not exist, it may be wrapped. The miss is cached if NegativeTTL is set
+//
+// 加载函数在值不存在时返回这个错误，可以被包装。如果设置了 NegativeTTL，未命中也会被缓存
+var ErrNotFound = errors.New("cache: value not found")
+
+const (
+	//缓存值的标记
+	flagValue = 'v'
+	//未命中的标记
+	flagMiss = 'n'
+)
+
+// Loader load the value when it is not cached
+//
+// 缓存不存在时加载值的函数
+type Loader func(ctx context.Context) (interface{}, error)
+
+// Cache read-through cache
+//
+// 读穿透缓存。缓存的值包含加载耗时，用于计算提前过期的概率。
+type Cache struct {
+	//key prefix. Default: "cache:"
+	//ssdb 中 key 的前缀，默认为 "cache:"
+	Prefix string
+	//XFetch beta, the larger the value, the earlier the refresh. 0 to disable. Default: 1
+	//提前过期的系数，值越大越早刷新，为 0 时不提前刷新。默认值: 1
+	Beta float64
+	//the ttl of the cached miss, 0 to disable negative caching. Default: 0
+	//未命中的缓存时间，为 0 时不缓存未命中。默认值: 0
+	NegativeTTL time.Duration
+	//The value is serialized by this function, default is the EncodingFunc of the connection pool
+	//值的序列化函数，默认使用连接池的 EncodingFunc
+	EncodingFunc func(v interface{}) []byte
+	//The value is deserialized by this function, default is json.Unmarshal, the same as client.Value.As
+	//值的反序列化函数，默认使用 json.Unmarshal，与 client.Value.As 一致
+	DecodingFunc func(data []byte, v interface{}) error
+	//连接池
+	pool *pool.Connectors
+	//合并加载
+	group group
+}
+
+// New create a cache
+//
+//	@param p connection pool
+//	@return *Cache
+//
+// 使用连接池创建一个缓存
+func New(p *pool.Connectors) *Cache {
+	return &Cache{
+		Prefix:       "cache:",
+		Beta:         1,
+		EncodingFunc: p.EncodingFunc,
+		DecodingFunc: json.Unmarshal,
+		pool:         p,
+	}
+}
+
+// 取一个连接，多个命令共用，使用完后需要关闭
+func (c *Cache) client() (*pool.Client, error) {
+	cc, err := c.pool.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	cc.AutoClose = false
+	return cc, nil
+}
+
+// GetOrLoad returns the cached value, or loads it with the loader and caches it for ttl.
+// Concurrent loads of the same key in the process are merged, and the value may be refreshed before it expires.
+//
+//	@param ctx context, it cancels the waiting, the loader gets a context without the cancellation since the load is shared
+//	@param key cache key
+//	@param ttl cache time, in seconds precision
+//	@param loader load function
+//	@return client.Value the serialized value, use As to convert it
+//	@return error ErrNotFound if the loader reported a miss (the error of the loader that wraps it for the call that loaded), or other possible error
+//
+// 返回缓存的值，如果不存在就使用 loader 加载并缓存 ttl 时长。进程内相同 key 的并发加载会合并，值可能在过期前被提前刷新。
+func (c *Cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader Loader) (client.Value, error) {
+	_, data, err := c.getOrLoad(ctx, key, ttl, loader)
+	if err != nil {
+		return "", err
+	}
+	return client.Value(data), nil
+}
+
+// Delete remove the cached value
+//
+//	@param key cache key
+//	@return error possible error, operation successfully returned nil
+//
+// 删除缓存的值
+func (c *Cache) Delete(key string) error {
+	cc, err := c.client()
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	return cc.Del(c.Prefix + key)
+}
+
+// 返回加载的原始值（仅在本次调用加载时有值）和序列化后的值
+func (c *Cache) getOrLoad(ctx context.Context, key string, ttl time.Duration, loader Loader) (interface{}, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	flag, data, err := c.get(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if flag == flagValue {
+		return nil, data, nil
+	}
+	if flag == flagMiss {
+		return nil, nil, ErrNotFound
+	}
+	return c.group.do(ctx, key, func(ctx context.Context) (interface{}, []byte, error) {
+		return c.load(ctx, key, ttl, loader)
+	})
+}
+
+// 读取缓存，返回标记和值，需要刷新时标记为 0
+func (c *Cache) get(key string) (byte, []byte, error) {
+	cc, err := c.client()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer cc.Close()
+	v, err := cc.Get(c.Prefix + key)
+	if err != nil {
+		return 0, nil, err
+	}
+	flag, delta, data, ok := decodeEntry(v.Bytes())
+	if !ok {
+		return 0, nil, nil
+	}
+	if c.Beta > 0 && delta > 0 {
+		remain, err := cc.TTL(c.Prefix + key)
+		if err != nil {
+			return 0, nil, err
+		}
+		if remain >= 0 && xfetch(delta, c.Beta, time.Duration(remain)*time.Second) {
+			return 0, nil, nil
+		}
+	}
+	return flag, data, nil
+}
+
+// 调用 loader 加载值并写入缓存
+func (c *Cache) load(ctx context.Context, key string, ttl time.Duration, loader Loader) (interface{}, []byte, error) {
+	start := time.Now()
+	v, err := loader(ctx)
+	delta := time.Since(start)
+	//loader 可能包装了 ErrNotFound
+	if errors.Is(err, ErrNotFound) {
+		if c.NegativeTTL > 0 {
+			if e := c.set(key, encodeEntry(flagMiss, delta, nil), c.NegativeTTL); e != nil {
+				return nil, nil, e
+			}
+		}
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	data := c.EncodingFunc(v)
+	if data == nil {
+		return nil, nil, goerr.String("cache %s cannot be serialized, please check EncodingFunc", key)
+	}
+	if err = c.set(key, encodeEntry(flagValue, delta, data), ttl); err != nil {
+		return nil, nil, err
+	}
+	return v, data, nil
+}
+
+func (c *Cache) set(key string, entry []byte, ttl time.Duration) error {
+	cc, err := c.client()
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	return cc.Set(c.Prefix+key, entry, seconds(ttl))
+}
+
+// 按 XFetch 算法判断是否需要提前刷新，delta 为加载耗时，remain 为剩余的有效期
+func xfetch(delta time.Duration, beta float64, remain time.Duration) bool {
+	r := 1 - rand.Float64() //(0,1]
+	return float64(delta)*beta*-math.Log(r) >= float64(remain)
+}
+
+// 缓存格式：标记 + 加载耗时（毫秒）+ 换行 + 值
+func encodeEntry(flag byte, delta time.Duration, data []byte) []byte {
+	bs := make([]byte, 0, len(data)+16)
+	bs = append(bs, flag)
+	bs = strconv.AppendInt(bs, int64(delta/time.Millisecond), 10)
+	bs = append(bs, '\n')
+	return append(bs, data...)
+}
+
+func decodeEntry(bs []byte) (flag byte, delta time.Duration, data []byte, ok bool) {
+	if len(bs) < 2 || (bs[0] != flagValue && bs[0] != flagMiss) {
+		return
+	}
+	for i := 1; i < len(bs); i++ {
+		if bs[i] == '\n' {
+			ms, err := strconv.ParseInt(string(bs[1:i]), 10, 64)
+			if err != nil {
+				return
+			}
+			return bs[0], time.Duration(ms) * time.Millisecond, bs[i+1:], true
+		}
+	}
+	return
+}
+
+// ssdb 的过期时间单位为秒，不足一秒按一秒计算
+func seconds(ttl time.Duration) int64 {
+	s := int64((ttl + time.Second - 1) / time.Second)
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
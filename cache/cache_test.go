@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+func TestEntry(t *testing.T) {
+	bs := encodeEntry(flagValue, 1500*time.Millisecond, []byte("abc\ndef"))
+	flag, delta, data, ok := decodeEntry(bs)
+	if !ok || flag != flagValue || delta != 1500*time.Millisecond || string(data) != "abc\ndef" {
+		t.Errorf("decode error %v %v %q %v", flag, delta, data, ok)
+	}
+	if _, _, _, ok = decodeEntry([]byte("abc")); ok {
+		t.Error("decode a invalid entry")
+	}
+}
+
+func TestXFetch(t *testing.T) {
+	if xfetch(time.Millisecond, 1, time.Hour) {
+		t.Error("refresh too early")
+	}
+	if !xfetch(time.Second, 1, 0) {
+		t.Error("expired entry is not refreshed")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	var g group
+	//第一个调用取消时，等待的调用仍然得到加载的结果
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_, _, _ = g.do(ctx, "k", func(ctx context.Context) (interface{}, []byte, error) {
+			close(started)
+			<-release
+			return nil, []byte("v"), ctx.Err()
+		})
+	}()
+	<-started
+	result := make(chan error, 1)
+	go func() {
+		_, data, err := g.do(context.Background(), "k", nil)
+		if err == nil && string(data) != "v" {
+			err = context.Canceled
+		}
+		result <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	close(release)
+	if err := <-result; err != nil {
+		t.Error("canceled by the first caller", err)
+	}
+	//加载 panic 时等待的调用得到错误，panic 仍然传递给第一个调用
+	started = make(chan struct{})
+	release = make(chan struct{})
+	panicked := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			panicked <- recover()
+		}()
+		_, _, _ = g.do(context.Background(), "k", func(ctx context.Context) (interface{}, []byte, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+	go func() {
+		_, _, err := g.do(context.Background(), "k", nil)
+		result <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if err := <-result; err == nil {
+		t.Error("panic is returned as success")
+	}
+	if e := <-panicked; e != "boom" {
+		t.Error("panic is not propagated", e)
+	}
+}
+
+type user struct {
+	Name string
+	Age  int
+}
+
+func TestGetOrLoad(t *testing.T) {
+	p := pool.NewConnectors(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	c := New(p)
+	c.NegativeTTL = time.Minute
+	_ = c.Delete("user:1")
+	_ = c.Delete("user:2")
+	var loads int32
+	loader := func(ctx context.Context) (user, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(10 * time.Millisecond)
+		return user{Name: "seefan", Age: 18}, nil
+	}
+	var wait sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			u, err := GetOrLoadAs(context.Background(), c, "user:1", time.Hour, loader)
+			if err != nil || u.Name != "seefan" {
+				t.Error(u, err)
+			}
+		}()
+	}
+	wait.Wait()
+	if loads != 1 {
+		t.Errorf("loaded %d times", loads)
+	}
+	v, err := c.GetOrLoad(context.Background(), "user:1", time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var u user
+	if err = v.As(&u); err != nil || u.Age != 18 {
+		t.Error(u, err)
+	}
+	miss := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		return nil, ErrNotFound
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = c.GetOrLoad(context.Background(), "user:2", time.Hour, miss); err != ErrNotFound {
+			t.Error(err)
+		}
+	}
+	if loads != 2 {
+		t.Errorf("miss is not cached, loaded %d times", loads)
+	}
+	//包装的 ErrNotFound 也缓存未命中
+	_ = c.Delete("user:3")
+	wrapped := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		return nil, fmt.Errorf("user 3: %w", ErrNotFound)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = c.GetOrLoad(context.Background(), "user:3", time.Hour, wrapped); !errors.Is(err, ErrNotFound) {
+			t.Error(err)
+		}
+	}
+	if loads != 3 {
+		t.Errorf("wrapped miss is not cached, loaded %d times", loads)
+	}
+	//合并的调用各自反序列化，不共享加载的值
+	_ = c.Delete("user:4")
+	loaded := &user{Name: "seefan"}
+	release := make(chan struct{})
+	shared := func(ctx context.Context) (*user, error) {
+		<-release
+		return loaded, nil
+	}
+	users := make(chan *user, 4)
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			u, err := GetOrLoadAs(context.Background(), c, "user:4", time.Hour, shared)
+			if err != nil {
+				t.Error(err)
+			}
+			users <- u
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wait.Wait()
+	close(users)
+	same := 0
+	for u := range users {
+		if u == loaded {
+			same++
+		} else if u == nil || u.Name != "seefan" {
+			t.Error(u)
+		}
+	}
+	if same > 1 {
+		t.Errorf("the loaded value is shared by %d calls", same)
+	}
+}
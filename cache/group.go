@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/seefan/goerr"
+)
+
+// 正在进行中的加载
+type call struct {
+	done chan struct{}
+	//加载结果
+	value interface{}
+	//序列化后的结果
+	data []byte
+	err  error
+}
+
+// group in-process singleflight, the concurrent loads of the same key are merged into one
+//
+// 进程内的合并加载，相同 key 的并发加载只执行一次
+type group struct {
+	lock  sync.Mutex
+	calls map[string]*call
+}
+
+// 执行加载，相同 key 的调用等待第一个调用的结果。等待的调用可以被自己的 ctx 取消。
+// 加载使用不随第一个调用取消的 ctx，第一个调用取消时不影响等待的调用；加载 panic 时等待的调用返回错误。
+// 加载的原始值只返回给第一个调用，等待的调用只得到序列化后的值，各自反序列化，不共享同一个值。
+func (g *group) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, []byte, error)) (interface{}, []byte, error) {
+	g.lock.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.lock.Unlock()
+		select {
+		case <-c.done:
+			return nil, c.data, c.err
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.lock.Unlock()
+
+	defer func() {
+		e := recover()
+		if e != nil {
+			c.value, c.data, c.err = nil, nil, goerr.String("cache load %s panic: %v", key, e)
+		}
+		g.lock.Lock()
+		delete(g.calls, key)
+		g.lock.Unlock()
+		close(c.done)
+		if e != nil {
+			panic(e)
+		}
+	}()
+	c.value, c.data, c.err = fn(context.WithoutCancel(ctx))
+	return c.value, c.data, c.err
+}
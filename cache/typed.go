@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/seefan/goerr"
+)
+
+// GetOrLoadAs the typed variant of GetOrLoad, the cached value is decoded with the DecodingFunc of the cache.
+// Only the call that ran the loader gets the loaded value itself, the merged calls decode their own copies.
+//
+//	@param ctx context
+//	@param c the cache
+//	@param key cache key
+//	@param ttl cache time, in seconds precision
+//	@param loader load function
+//	@return T the value
+//	@return error ErrNotFound if the loader reported a miss, or other possible error
+//
+// GetOrLoad 的泛型版本，缓存的值使用 DecodingFunc 反序列化为指定类型。
+// 只有执行加载的调用得到 loader 返回的值本身，合并的调用各自反序列化，不共享同一个值。
+func GetOrLoadAs[T any](ctx context.Context, c *Cache, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	var re T
+	v, data, err := c.getOrLoad(ctx, key, ttl, func(ctx context.Context) (interface{}, error) {
+		return loader(ctx)
+	})
+	if err != nil {
+		return re, err
+	}
+	//本次加载的值直接返回，不用再反序列化
+	if tv, ok := v.(T); ok {
+		return tv, nil
+	}
+	if err = c.DecodingFunc(data, &re); err != nil {
+		return re, goerr.Errorf(err, "cache %s cannot be deserialized", key)
+	}
+	return re, nil
+}
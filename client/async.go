@@ -0,0 +1,355 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/seefan/goerr"
+)
+
+// Future the result of an asynchronous command
+//
+// 异步命令的结果
+type Future[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// Done returns a channel closed when the result is ready
+//
+// 返回一个通道，结果就绪时关闭
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait wait for the result until the context is done, the command is not canceled if the context is done
+//
+//	@param ctx context
+//	@return T result
+//	@return error possible error, operation successfully returned nil
+//
+// 等待结果直到上下文结束，上下文结束时命令并不会被取消
+func (f *Future[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Async the asynchronous api of a client, the commands are pipelined on the connection and return futures, goroutine safe.
+// The middlewares and the observer are not called for the asynchronous commands.
+// Do not use the synchronous functions of the client until Close returns.
+//
+// 连接的异步接口，命令以流水线方式在连接上发送并返回 Future，协程安全。异步命令不经过中间件和 Observer。
+// 在 Close 返回前不要使用连接的同步函数。
+type Async struct {
+	c    *Client
+	lock sync.Mutex
+	//保证命令按登记的顺序发送，发送时不持有 lock
+	sendLock sync.Mutex
+	//等待所有命令完成
+	idle *sync.Cond
+	//已发送等待响应的命令的回调，按发送顺序
+	pending []func(resp []string, err error)
+	//是否有接收协程在运行
+	reading bool
+	//连接出错的原因，出错后连接被关闭，后续命令直接返回该错误
+	err error
+	//已和连接分离，连接可能已归还连接池
+	detached bool
+}
+
+// ErrAsyncDetached the asynchronous api is used after the client is returned to the pool
+//
+// 连接归还连接池后继续使用异步接口
+var ErrAsyncDetached = errors.New("the asynchronous api is detached from the client")
+
+// ErrAsyncUnsupported the client has no socket of its own, such as a client created by NewHandlerClient or the typed client of mux
+//
+// 连接没有自己的 socket，例如 NewHandlerClient 创建的连接和 mux 的类型化连接，不支持异步接口
+var ErrAsyncUnsupported = errors.New("the asynchronous api needs a client with its own socket")
+
+// Async returns the asynchronous api of the client, goroutine safe, all calls before DetachAsync return the same value.
+// The client must own a socket: for a client created by NewHandlerClient, such as the typed client of mux,
+// or a client not created by NewClient, all the commands return ErrAsyncUnsupported, or the error of the client if it has one.
+//
+//	@return *Async
+//
+// 返回连接的异步接口，协程安全，DetachAsync 之前的调用返回同一个值。
+// 连接需要有自己的 socket：NewHandlerClient 创建的连接（例如 mux 的类型化连接）或不是 NewClient 创建的连接，
+// 所有命令返回 ErrAsyncUnsupported，连接有错误时返回该错误。
+func (c *Client) Async() *Async {
+	if c.asyncLock == nil || c.base != nil {
+		err := c.Error
+		if err == nil {
+			err = ErrAsyncUnsupported
+		}
+		a := &Async{c: c, err: err, detached: true}
+		a.idle = sync.NewCond(&a.lock)
+		return a
+	}
+	c.asyncLock.Lock()
+	defer c.asyncLock.Unlock()
+	if c.async == nil {
+		c.async = &Async{c: c}
+		c.async.idle = sync.NewCond(&c.async.lock)
+	}
+	return c.async
+}
+
+// DetachAsync wait for the pending asynchronous commands and detach the asynchronous api from the client,
+// the detached api returns ErrAsyncDetached. It is used by the pool before the client is reused.
+//
+//	@return error the connection error of the asynchronous commands, the connection is closed if not nil
+//
+// 等待所有已发送的异步命令完成，并把异步接口和连接分离，分离后的异步接口返回 ErrAsyncDetached。连接池在复用连接前调用。
+func (c *Client) DetachAsync() error {
+	if c.asyncLock == nil {
+		//不是 NewClient 创建的连接没有异步接口
+		return nil
+	}
+	c.asyncLock.Lock()
+	a := c.async
+	c.async = nil
+	c.asyncLock.Unlock()
+	if a == nil {
+		return nil
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for a.reading {
+		a.idle.Wait()
+	}
+	a.detached = true
+	err := a.err
+	a.err = ErrAsyncDetached
+	return err
+}
+
+// Close wait for the pending commands, then release the client like a synchronous command does if AutoClose is set
+//
+//	@return error the connection error of the asynchronous commands, nil if no error
+//
+// 等待所有已发送的命令完成，之后如果设置了 AutoClose，像同步命令一样回收连接
+func (a *Async) Close() error {
+	a.lock.Lock()
+	for a.reading {
+		a.idle.Wait()
+	}
+	if a.detached {
+		//连接已经归还或不支持异步接口，不能回收
+		err := a.err
+		a.lock.Unlock()
+		return err
+	}
+	err := a.err
+	a.err = nil
+	a.lock.Unlock()
+	if a.c.closeMethod != nil {
+		a.c.closeMethod()
+	}
+	return err
+}
+
+// 发送命令并登记回调，响应由接收协程按顺序交给回调
+func (a *Async) send(args []interface{}, done func(resp []string, err error)) {
+	//发送按登记的顺序进行，发送时不持有 lock，接收协程可以同时读取之前命令的响应
+	a.sendLock.Lock()
+	defer a.sendLock.Unlock()
+	a.lock.Lock()
+	if a.err == nil && a.c.Error != nil {
+		a.err = a.c.Error
+	}
+	if err := a.err; err != nil {
+		a.lock.Unlock()
+		done(nil, err)
+		return
+	}
+	a.pending = append(a.pending, done)
+	if !a.reading {
+		a.reading = true
+		go a.read()
+	}
+	a.lock.Unlock()
+	if err := a.c.SSDBClient.Send(args...); err != nil {
+		//可能只发送了一部分，连接不能再使用，中断接收协程，由它通知所有等待的命令并关闭连接
+		a.lock.Lock()
+		if a.err == nil {
+			a.err = err
+		}
+		a.lock.Unlock()
+		a.c.SSDBClient.Interrupt()
+	}
+}
+
+// 接收协程，没有等待的命令时退出，出错时关闭连接并通知所有等待的命令
+func (a *Async) read() {
+	for {
+		a.lock.Lock()
+		if a.err != nil || len(a.pending) == 0 {
+			if a.err != nil {
+				for _, done := range a.pending {
+					done(nil, a.err)
+				}
+				a.pending = nil
+				_ = a.c.SSDBClient.Close()
+			}
+			a.reading = false
+			a.idle.Broadcast()
+			a.lock.Unlock()
+			return
+		}
+		done := a.pending[0]
+		a.pending[0] = nil
+		a.pending = a.pending[1:]
+		a.lock.Unlock()
+
+		resp, err := a.c.SSDBClient.Recv()
+		if err != nil {
+			a.lock.Lock()
+			if a.err == nil {
+				a.err = err
+			}
+			err = a.err
+			a.lock.Unlock()
+		}
+		done(resp, err)
+	}
+}
+
+// 发送命令并创建 Future，parse 把响应转换为结果
+func async[T any](a *Async, parse func(resp []string) (T, error), args ...interface{}) *Future[T] {
+	f := &Future[T]{done: make(chan struct{})}
+	a.send(args, func(resp []string, err error) {
+		if err == nil {
+			f.val, f.err = parse(resp)
+		} else {
+			f.err = err
+		}
+		close(f.done)
+	})
+	return f
+}
+
+// 解析只返回状态的响应
+func parseOK(errKey ...interface{}) func(resp []string) (struct{}, error) {
+	return func(resp []string) (struct{}, error) {
+		if len(resp) > 0 && resp[0] == oK {
+			return struct{}{}, nil
+		}
+		return struct{}{}, makeError(resp, errKey...)
+	}
+}
+
+// 解析返回一个值的响应
+func parseValue(errKey ...interface{}) func(resp []string) (Value, error) {
+	return func(resp []string) (Value, error) {
+		if len(resp) == 2 && resp[0] == oK {
+			return Value(resp[1]), nil
+		}
+		return "", makeError(resp, errKey...)
+	}
+}
+
+// 解析返回一个整数的响应
+func parseInt64(errKey ...interface{}) func(resp []string) (int64, error) {
+	return func(resp []string) (int64, error) {
+		if len(resp) == 2 && resp[0] == oK {
+			return Value(resp[1]).Int64(), nil
+		}
+		return 0, makeError(resp, errKey...)
+	}
+}
+
+// Do send a command, see Client.Do
+//
+//	@param args the command and the arguments
+//	@return *Future[[]string] the response
+//
+// 异步执行一个命令，返回原始响应
+func (a *Async) Do(args ...interface{}) *Future[[]string] {
+	if len(args) == 0 {
+		f := &Future[[]string]{done: make(chan struct{}), err: goerr.String("command is empty")}
+		close(f.done)
+		return f
+	}
+	return async(a, func(resp []string) ([]string, error) {
+		return resp, nil
+	}, args...)
+}
+
+// Get see Client.Get
+//
+// 异步获取指定 key 的值，key 不存在时返回空值
+func (a *Async) Get(key string) *Future[Value] {
+	return async(a, parseValue(key), "get", key)
+}
+
+// Set see Client.Set
+//
+// 异步设置指定 key 的值，ttl 为可选的过期时间，单位为秒
+func (a *Async) Set(key string, val interface{}, ttl ...int64) *Future[struct{}] {
+	if len(ttl) > 0 {
+		return async(a, parseOK(key), "setx", key, val, ttl[0])
+	}
+	return async(a, parseOK(key), "set", key, val)
+}
+
+// Del see Client.Del
+//
+// 异步删除指定 key
+func (a *Async) Del(key string) *Future[struct{}] {
+	return async(a, parseOK(key), "del", key)
+}
+
+// Exists see Client.Exists
+//
+// 异步检查指定 key 是否存在
+func (a *Async) Exists(key string) *Future[bool] {
+	return async(a, func(resp []string) (bool, error) {
+		if len(resp) == 2 && resp[0] == oK {
+			return resp[1] == "1", nil
+		}
+		return false, makeError(resp, key)
+	}, "exists", key)
+}
+
+// Incr see Client.Incr
+//
+// 异步使 key 对应的值加上 num
+func (a *Async) Incr(key string, num int64) *Future[int64] {
+	return async(a, parseInt64(key), "incr", key, num)
+}
+
+// HGet see Client.HGet
+//
+// 异步获取 hashmap 中指定 key 的值
+func (a *Async) HGet(setName, key string) *Future[Value] {
+	return async(a, parseValue(setName, key), "hget", setName, key)
+}
+
+// HSet see Client.HSet
+//
+// 异步设置 hashmap 中指定 key 的值
+func (a *Async) HSet(setName, key string, value interface{}) *Future[struct{}] {
+	return async(a, parseOK(setName, key), "hset", setName, key, value)
+}
+
+// ZGet see Client.ZGet
+//
+// 异步获取 zset 中指定 key 的权重
+func (a *Async) ZGet(setName, key string) *Future[int64] {
+	return async(a, parseInt64(setName, key), "zget", setName, key)
+}
+
+// ZSet see Client.ZSet
+//
+// 异步设置 zset 中指定 key 的权重
+func (a *Async) ZSet(setName, key string, score int64) *Future[struct{}] {
+	return async(a, parseOK(setName, key), "zset", setName, key, score)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 
 	"github.com/seefan/gossdb/v2/ssdbclient"
 )
@@ -18,7 +19,7 @@ const (
 //Client client
 //
 //可回收的连接，支持连接池。
-//非协程安全，多协程请使用多个连接。
+//非协程安全，多协程请使用多个连接，或使用 mux 包的多路复用连接。
 type Client struct {
 	//socket client
 	ssdbclient.SSDBClient
@@ -31,6 +32,24 @@ type Client struct {
 	//tmp error
 	//临时的错误信息，系统用
 	Error error
+	//middlewares
+	//中间件
+	middlewares []Middleware
+	//the handler wrapped by the middlewares
+	//中间件包装后的处理函数
+	handler Handler
+	//the handler executing the commands instead of the socket, see NewHandlerClient
+	//代替 socket 执行命令的处理函数，见 NewHandlerClient
+	base Handler
+	//the asynchronous api, see Async
+	//异步接口，见 Async
+	async *Async
+	//protect async, a pointer because the pool copies the client
+	//保护 async，使用指针，连接池会复制 Client
+	asyncLock *sync.Mutex
+	//reopen the closed connection before a command, see SetReopen
+	//执行命令前重新打开已关闭的连接，见 SetReopen
+	reopen func() error
 }
 
 //NewClient create new client
@@ -44,6 +63,7 @@ func NewClient(c *ssdbclient.SSDBClient, closeMethod func()) *Client {
 	return &Client{
 		SSDBClient:  *c,
 		closeMethod: closeMethod,
+		asyncLock:   &sync.Mutex{},
 	}
 }
 
@@ -64,15 +84,29 @@ func (c *Client) Do(args ...interface{}) (rsp []string, err error) {
 			c.closeMethod()
 		}
 	}()
-	if !c.SSDBClient.IsOpen() {
-		return nil, errors.New("use the closed connection")
+	if c.base == nil && !c.SSDBClient.IsOpen() {
+		if c.reopen == nil {
+			return nil, errors.New("use the closed connection")
+		}
+		if err = c.reopen(); err != nil {
+			return nil, err
+		}
 	}
 
-	rsp, err = c.SSDBClient.Do(args...)
+	rsp, err = c.do(args)
 
 	return
 }
 
+//SetReopen set the function called by Do when the connection is closed, it reopens the connection or returns an error
+//
+//  @param fn reopen the connection, nil to return an error for the closed connection
+//
+//  设置连接已关闭时 Do 调用的函数，由它重新打开连接或返回错误
+func (c *Client) SetReopen(fn func() error) {
+	c.reopen = fn
+}
+
 //Ping ping ssdb
 //
 //  @return ssdb is available
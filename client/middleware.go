@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Handler executes a command, cmd is the command name and args are the arguments after it
+//
+// 执行一个命令，cmd 为命令名称，args 为命令后面的参数
+type Handler func(ctx context.Context, cmd string, args ...interface{}) ([]string, error)
+
+// Middleware wraps a handler, used for logging, metrics, key validation, fault injection, command blocking and so on
+//
+// 包装一个 Handler，可以用于日志、统计、key 校验、故障注入、命令拦截等
+type Middleware func(next Handler) Handler
+
+// Use add middlewares to the client, the first one is the outermost
+//
+//	@param mw middlewares
+//
+// 为连接添加中间件，先添加的在最外层
+func (c *Client) Use(mw ...Middleware) {
+	if len(mw) == 0 {
+		return
+	}
+	c.middlewares = append(c.middlewares, mw...)
+	h := c.base
+	if h == nil {
+		h = func(ctx context.Context, cmd string, args ...interface{}) ([]string, error) {
+			return c.SSDBClient.Do(append([]interface{}{cmd}, args...)...)
+		}
+	}
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	c.handler = h
+}
+
+// NewHandlerClient create a client whose commands are executed by the handler instead of a socket,
+// it is used to provide the typed functions for other transports such as the multiplexed client
+//
+//	@param h the handler executing the commands
+//	@return *Client
+//
+// 创建一个由 handler 执行命令的连接，不使用自己的 socket，用于为多路复用连接等其它传输方式提供类型化的函数
+func NewHandlerClient(h Handler) *Client {
+	return &Client{
+		base:      h,
+		handler:   h,
+		asyncLock: &sync.Mutex{},
+	}
+}
+
+// 执行命令，有中间件时经过中间件
+func (c *Client) do(args []interface{}) ([]string, error) {
+	if c.handler == nil {
+		return c.SSDBClient.Do(args...)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("command is empty")
+	}
+	cmd, _ := args[0].(string)
+	return c.handler(c.Context(), cmd, args[1:]...)
+}
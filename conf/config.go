@@ -1,6 +1,10 @@
 // Package conf gossdb config
 package conf
 
+import (
+	"log/slog"
+)
+
 // Config gossdb config
 //
 // ssdb连接池的配置
@@ -59,6 +63,68 @@ type Config struct {
 	//if retry is enabled, set to true and try again if the request fails.
 	//是否启用重试，设置为true时，如果连接状态异常会重新连接一次。
 	RetryEnabled bool
+	//maximum number of attempts of a command when retry is enabled, including the first one. Default: 2
+	//启用重试时一个命令最多执行的次数，包括第一次。默认值: 2
+	RetryMaxAttempts int
+	//backoff before the first retry in milliseconds, doubled for each following retry. Default: 10
+	//第一次重试前等待的时间，单位为毫秒，之后每次翻倍。默认值: 10
+	RetryBackoffMillisecond int
+	//maximum backoff between retries in milliseconds. Default: 1000
+	//重试之间最长的等待时间，单位为毫秒。默认值: 1000
+	RetryMaxBackoffMillisecond int
+	//the commands slower than this value are recorded in the slow log, in milliseconds. 0 to disable. Default: 0
+	//执行时间超过本值的命令将记录到慢日志中，单位为毫秒，为 0 时不记录。默认值: 0
+	SlowLogMillisecond int
+	//maximum number of entries in the slow log. Default: 128
+	//慢日志最多保存的条数。默认值: 128
+	SlowLogSize int
+	//the circuit breaker opens after this number of consecutive connection failures, 0 to disable. Default: 0
+	//连续出现本值次连接错误后熔断器打开，为 0 时不启用。默认值: 0
+	BreakerFailures int
+	//the circuit breaker opens when the percentage of the connection failures in the window reaches this value, 0 to disable. Default: 0
+	//统计窗口内连接错误的百分比达到本值时熔断器打开，为 0 时不启用。默认值: 0
+	BreakerErrorRate int
+	//minimum number of commands in the window to check the error rate. Default: 20
+	//统计窗口内检查错误率的最少命令数。默认值: 20
+	BreakerMinRequests int
+	//window of the error rate in seconds. Default: 10
+	//错误率的统计窗口，单位为秒。默认值: 10
+	BreakerWindowSecond int
+	//time the circuit breaker stays open before probing with Ping, in seconds. Default: 5
+	//熔断器打开后等待多久再用 Ping 探测，单位为秒。默认值: 5
+	BreakerOpenSecond int
+	//if true, Start does not open the connections, they are opened in the background and on demand, and the startup succeeds with partial connectivity. Default: false
+	//是否延迟启动，开启后 Start 不会打开连接，连接在后台预热或在取出时打开，部分连接失败时也可以启动。默认值: false
+	LazyStart bool
+	//maximum lifetime of a connection in seconds, the expired connections are closed when idle and reopened when taken out. 0 to disable. Default: 0
+	//连接的最长寿命，单位为秒，超过的连接在空闲时关闭，取出时重新打开，为 0 时不限制。默认值: 0
+	MaxConnLifetime int
+	//maximum idle time of a connection in seconds, 0 to disable. Default: 0
+	//连接的最长空闲时间，单位为秒，为 0 时不限制。默认值: 0
+	MaxIdleTime int
+	//maximum number of times a connection is taken out before it is reopened, 0 to disable. Default: 0
+	//连接被取出多少次后重新打开，为 0 时不限制。默认值: 0
+	MaxUsesPerConn int
+	//the clients held longer than this value in seconds are reported as leaks with the acquiring stack trace, 0 to disable. Default: 0
+	//连接持有时间超过本值时作为泄漏报告，并记录取出连接时的调用栈，单位为秒，为 0 时不启用。默认值: 0
+	LeakDetectSecond int
+	//if true, the leaked clients are closed and replaced in the pool, the holder gets pool.ErrLeakReclaimed. Default: false
+	//是否强制回收泄漏的连接，开启后泄漏的连接将被关闭并在连接池中替换，持有者再使用时返回 pool.ErrLeakReclaimed。默认值: false
+	LeakReclaim bool
+	//percentile of the recent latencies of a read-only command, after which the command is sent again on another idle connection, 0 to disable. Default: 0
+	//只读命令超过最近耗时的该百分位数仍未返回时，用另一个空闲连接再发送一次（对冲读），为 0 时不启用。默认值: 0
+	HedgePercentile int
+	//minimum delay before hedging a read in milliseconds. Default: 1
+	//对冲读的最小延迟，单位为毫秒。默认值: 1
+	HedgeMinDelayMillisecond int
+	//if true, the available connections of a pool block are kept in a lock-free ring instead of a queue under a mutex, Get and Set of a pool block take no lock.
+	//It is not faster than the queue in the benchmarks of the pool package (BenchmarkPool_Ring, BenchmarkPool_Queue), measure on the target machine before enabling it. Default: false
+	//是否使用无锁的环形队列保存连接池块的空闲连接，默认使用加锁的队列，开启后连接池块取出和归还连接时不加锁。
+	//pool 包的基准测试中无锁队列并不比加锁的队列快（BenchmarkPool_Ring、BenchmarkPool_Queue），开启前请在目标机器上测试。默认值: false
+	LockFreeQueue bool
+	//optional logger, the slow commands and the lifecycle events of the pool and connections are logged to it if set
+	//可选的日志，设置后慢命令以及连接池和连接的生命周期事件会输出到这里
+	Logger *slog.Logger
 }
 
 // Default Gets the default configuration parameters
@@ -79,6 +145,14 @@ func (c *Config) Default() *Config {
 	c.ReadBufferSize = defaultValue(c.ReadBufferSize, 8)
 	c.ReadWriteTimeout = defaultValue(c.ReadWriteTimeout, 60)
 	c.ConnectTimeout = defaultValue(c.ConnectTimeout, 5)
+	c.SlowLogSize = defaultValue(c.SlowLogSize, 128)
+	c.RetryMaxAttempts = defaultValue(c.RetryMaxAttempts, 2)
+	c.RetryBackoffMillisecond = defaultValue(c.RetryBackoffMillisecond, 10)
+	c.RetryMaxBackoffMillisecond = defaultValue(c.RetryMaxBackoffMillisecond, 1000)
+	c.BreakerMinRequests = defaultValue(c.BreakerMinRequests, 20)
+	c.BreakerWindowSecond = defaultValue(c.BreakerWindowSecond, 10)
+	c.BreakerOpenSecond = defaultValue(c.BreakerOpenSecond, 5)
+	c.HedgeMinDelayMillisecond = defaultValue(c.HedgeMinDelayMillisecond, 1)
 	if c.MinPoolSize < c.PoolSize {
 		c.MinPoolSize = c.PoolSize
 	}
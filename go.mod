@@ -1,5 +1,5 @@
 module github.com/seefan/gossdb/v2
 
-go 1.14
+go 1.21
 
 require github.com/seefan/goerr v1.1.2
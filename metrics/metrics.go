@@ -0,0 +1,171 @@
+// Package metrics dependency-free monotonic counters and histograms in the prometheus text exposition format
+//
+// 不依赖第三方库的计数器和直方图，以 prometheus 文本格式输出
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets default latency buckets in seconds
+//
+// 默认的耗时分桶，单位为秒
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Counter monotonic counter
+//
+// 只增不减的计数器
+type Counter struct {
+	value uint64
+}
+
+// Inc add one
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.value, 1)
+}
+
+// Add add n
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.value, n)
+}
+
+// Value returns the current value
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.value)
+}
+
+// GaugeFunc gauge whose value is read from the function when exported
+//
+// 导出时从函数读取值的仪表
+type GaugeFunc func() float64
+
+// Histogram histogram with fixed buckets
+//
+// 固定分桶的直方图
+type Histogram struct {
+	//分桶的上界，升序
+	buckets []float64
+	//每个分桶的计数，最后一个为 +Inf，不累计
+	counts []uint64
+	//合计值，float64 的二进制
+	sum   uint64
+	count uint64
+}
+
+// NewHistogram create a histogram
+//
+//	@param buckets upper bounds of the buckets, DefaultBuckets if empty
+//	@return *Histogram
+//
+// 创建直方图，不指定分桶时使用 DefaultBuckets
+func NewHistogram(buckets ...float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	bs := append([]float64(nil), buckets...)
+	sort.Float64s(bs)
+	return &Histogram{
+		buckets: bs,
+		counts:  make([]uint64, len(bs)+1),
+	}
+}
+
+// Observe add a observation
+//
+// 记录一个值
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	for {
+		old := atomic.LoadUint64(&h.sum)
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&h.sum, old, sum) {
+			return
+		}
+	}
+}
+
+// Snapshot returns the cumulative bucket counts, the sum and the count
+//
+//	@return []uint64 cumulative count of each bucket, the last one is +Inf
+//	@return float64 sum
+//	@return uint64 count
+//
+// 返回累计的分桶计数、合计值和总个数
+func (h *Histogram) Snapshot() ([]uint64, float64, uint64) {
+	counts := make([]uint64, len(h.counts))
+	var total uint64
+	for i := range h.counts {
+		total += atomic.LoadUint64(&h.counts[i])
+		counts[i] = total
+	}
+	return counts, math.Float64frombits(atomic.LoadUint64(&h.sum)), total
+}
+
+// CounterVec counters partitioned by one label
+//
+// 按一个标签区分的一组计数器
+type CounterVec struct {
+	label string
+	m     sync.Map
+}
+
+// NewCounterVec create a counter vector
+func NewCounterVec(label string) *CounterVec {
+	return &CounterVec{label: label}
+}
+
+// With returns the counter of the label value
+//
+// 返回标签值对应的计数器
+func (v *CounterVec) With(value string) *Counter {
+	if c, ok := v.m.Load(value); ok {
+		return c.(*Counter)
+	}
+	c, _ := v.m.LoadOrStore(value, &Counter{})
+	return c.(*Counter)
+}
+
+// HistogramVec histograms partitioned by one label
+//
+// 按一个标签区分的一组直方图
+type HistogramVec struct {
+	label   string
+	buckets []float64
+	m       sync.Map
+}
+
+// NewHistogramVec create a histogram vector
+func NewHistogramVec(label string, buckets ...float64) *HistogramVec {
+	return &HistogramVec{label: label, buckets: buckets}
+}
+
+// With returns the histogram of the label value
+//
+// 返回标签值对应的直方图
+func (v *HistogramVec) With(value string) *Histogram {
+	if h, ok := v.m.Load(value); ok {
+		return h.(*Histogram)
+	}
+	h, _ := v.m.LoadOrStore(value, NewHistogram(v.buckets...))
+	return h.(*Histogram)
+}
+
+// 按标签值排序遍历
+func rangeSorted(m *sync.Map, fn func(key string, value interface{})) {
+	var keys []string
+	m.Range(func(k, _ interface{}) bool {
+		keys = append(keys, k.(string))
+		return true
+	})
+	sort.Strings(keys)
+	for _, k := range keys {
+		if v, ok := m.Load(k); ok {
+			fn(k, v)
+		}
+	}
+}
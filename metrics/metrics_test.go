@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram(1, 2, 5)
+	for _, v := range []float64{0.5, 1, 1.5, 3, 10} {
+		h.Observe(v)
+	}
+	counts, sum, count := h.Snapshot()
+	want := []uint64{2, 3, 4, 5}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("bucket %d is %d, want %d", i, counts[i], want[i])
+		}
+	}
+	if sum != 16 || count != 5 {
+		t.Errorf("sum %v count %d", sum, count)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	c := &Counter{}
+	c.Add(3)
+	r.Register("test_total", "Test counter.", c)
+	v := NewCounterVec("kind")
+	v.With("b\"").Inc()
+	v.With("a").Inc()
+	r.Register("test_errors_total", "Test errors.", v)
+	h := NewHistogramVec("command", 1)
+	h.With("get").Observe(0.5)
+	r.Register("test_seconds", "Test histogram.", h)
+	r.Register("test_gauge", "Test gauge.", GaugeFunc(func() float64 { return 1.5 }))
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_total Test counter.
+# TYPE test_total counter
+test_total 3
+# HELP test_errors_total Test errors.
+# TYPE test_errors_total counter
+test_errors_total{kind="a"} 1
+test_errors_total{kind="b\""} 1
+# HELP test_seconds Test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{command="get",le="1"} 1
+test_seconds_bucket{command="get",le="+Inf"} 1
+test_seconds_sum{command="get"} 0.5
+test_seconds_count{command="get"} 1
+# HELP test_gauge Test gauge.
+# TYPE test_gauge gauge
+test_gauge 1.5
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 注册的指标
+type metric struct {
+	name  string
+	help  string
+	value interface{}
+}
+
+// Registry a set of metrics, it is a http.Handler of the prometheus text exposition format
+//
+// 一组指标，可以作为 http.Handler 以 prometheus 文本格式输出
+type Registry struct {
+	lock    sync.RWMutex
+	metrics []metric
+}
+
+// NewRegistry create a registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register register a metric, the value is one of *Counter, GaugeFunc, *Histogram, *CounterVec, *HistogramVec
+//
+//	@param name metric name
+//	@param help help text
+//	@param value the metric
+//
+// 注册一个指标
+func (r *Registry) Register(name, help string, value interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.metrics = append(r.metrics, metric{name: name, help: help, value: value})
+}
+
+// WriteTo write all metrics in the prometheus text exposition format
+//
+//	@param w writer
+//	@return int64 bytes written
+//	@return error possible error, operation successfully returned nil
+//
+// 以 prometheus 文本格式输出所有的指标
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	for _, m := range r.metrics {
+		m.write(cw)
+	}
+	if err := cw.w.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP http handler
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = r.WriteTo(w)
+}
+
+func (m metric) write(w *countWriter) {
+	switch v := m.value.(type) {
+	case *Counter:
+		w.header(m, "counter")
+		w.sample(m.name, "", "", strconv.FormatUint(v.Value(), 10))
+	case GaugeFunc:
+		w.header(m, "gauge")
+		w.sample(m.name, "", "", formatFloat(v()))
+	case *Histogram:
+		w.header(m, "histogram")
+		w.histogram(m.name, "", "", v)
+	case *CounterVec:
+		w.header(m, "counter")
+		rangeSorted(&v.m, func(key string, c interface{}) {
+			w.sample(m.name, v.label, key, strconv.FormatUint(c.(*Counter).Value(), 10))
+		})
+	case *HistogramVec:
+		w.header(m, "histogram")
+		rangeSorted(&v.m, func(key string, h interface{}) {
+			w.histogram(m.name, v.label, key, h.(*Histogram))
+		})
+	}
+}
+
+// 记录写入的字节数和第一个错误
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countWriter) write(s ...string) {
+	for _, v := range s {
+		if w.err != nil {
+			return
+		}
+		n, err := w.w.WriteString(v)
+		w.n += int64(n)
+		w.err = err
+	}
+}
+
+func (w *countWriter) header(m metric, typ string) {
+	w.write("# HELP ", m.name, " ", escape(m.help, false), "\n# TYPE ", m.name, " ", typ, "\n")
+}
+
+// 输出一个值，标签名为空时不输出标签
+func (w *countWriter) sample(name, label, labelValue, value string) {
+	w.samplef(name, "", []string{label, labelValue}, value)
+}
+
+// 输出一个值，labels 为成对的标签名和标签值
+func (w *countWriter) samplef(name, le string, labels []string, value string) {
+	w.write(name)
+	first := true
+	for i := 0; i+1 < len(labels); i += 2 {
+		if labels[i] == "" {
+			continue
+		}
+		w.label(&first, labels[i], labels[i+1])
+	}
+	if le != "" {
+		w.label(&first, "le", le)
+	}
+	if !first {
+		w.write("}")
+	}
+	w.write(" ", value, "\n")
+}
+
+func (w *countWriter) label(first *bool, name, value string) {
+	if *first {
+		w.write("{")
+		*first = false
+	} else {
+		w.write(",")
+	}
+	w.write(name, "=\"", escape(value, true), "\"")
+}
+
+func (w *countWriter) histogram(name, label, value string, h *Histogram) {
+	counts, sum, count := h.Snapshot()
+	labels := []string{label, value}
+	for i, b := range h.buckets {
+		w.samplef(name+"_bucket", formatFloat(b), labels, strconv.FormatUint(counts[i], 10))
+	}
+	w.samplef(name+"_bucket", "+Inf", labels, strconv.FormatUint(count, 10))
+	w.samplef(name+"_sum", "", labels, formatFloat(sum))
+	w.samplef(name+"_count", "", labels, strconv.FormatUint(count, 10))
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer("\\", `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer("\\", `\\`, "\n", `\n`, "\"", `\"`)
+)
+
+func escape(s string, label bool) string {
+	if label {
+		return labelEscaper.Replace(s)
+	}
+	return helpEscaper.Replace(s)
+}
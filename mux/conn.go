@@ -0,0 +1,124 @@
+package mux
+
+import (
+	"context"
+	"sync"
+
+	"github.com/seefan/gossdb/v2/ssdbclient"
+)
+
+// 一个等待响应的请求
+type call struct {
+	resp []string
+	err  error
+	done chan struct{}
+}
+
+// 流水线连接，发送在调用者的协程中进行，接收在独立的协程中按顺序进行
+type conn struct {
+	lock sync.Mutex
+	//保证请求按登记的顺序发送，发送时不持有 lock，接收协程不会被阻塞的写入卡住
+	sendLock sync.Mutex
+	cond     *sync.Cond
+	cli      *ssdbclient.SSDBClient
+	//已登记等待响应的请求，按发送顺序
+	pending []*call
+	//连接出错或关闭的原因，不为空时不能再发送
+	err error
+}
+
+func newConn(cli *ssdbclient.SSDBClient) *conn {
+	c := &conn{cli: cli}
+	c.cond = sync.NewCond(&c.lock)
+	go c.read()
+	return c
+}
+
+// 连接是否已经出错
+func (c *conn) failed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err != nil
+}
+
+// 发送一个命令并等待响应，先在 lock 下登记请求，再在 sendLock 下发送，保证请求和响应的顺序一致
+func (c *conn) do(ctx context.Context, args []interface{}) ([]string, error) {
+	cl := &call{done: make(chan struct{})}
+	c.sendLock.Lock()
+	c.lock.Lock()
+	if err := c.err; err != nil {
+		c.lock.Unlock()
+		c.sendLock.Unlock()
+		return nil, err
+	}
+	c.pending = append(c.pending, cl)
+	c.cond.Signal()
+	c.lock.Unlock()
+	if err := c.cli.Send(args...); err != nil {
+		//可能只发送了一部分，后续的响应都无法匹配，释放 sendLock 前设置错误，接收协程通知所有等待中的请求
+		c.lock.Lock()
+		c.fail(err)
+		c.lock.Unlock()
+		c.sendLock.Unlock()
+		return nil, err
+	}
+	c.sendLock.Unlock()
+	select {
+	case <-cl.done:
+		return cl.resp, cl.err
+	case <-ctx.Done():
+		//响应到达后丢弃
+		return nil, ctx.Err()
+	}
+}
+
+// 设置错误并中断接收，需要持有锁
+func (c *conn) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+	c.cli.Interrupt()
+	c.cond.Broadcast()
+}
+
+// 关闭连接，等待中的请求返回 err
+func (c *conn) close(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.fail(err)
+}
+
+// 接收协程，按发送的顺序接收响应，出错时所有等待中的请求返回错误并关闭连接
+func (c *conn) read() {
+	for {
+		c.lock.Lock()
+		for len(c.pending) == 0 && c.err == nil {
+			c.cond.Wait()
+		}
+		if c.err != nil {
+			for _, cl := range c.pending {
+				cl.err = c.err
+				close(cl.done)
+			}
+			c.pending = nil
+			_ = c.cli.Close()
+			c.lock.Unlock()
+			return
+		}
+		cl := c.pending[0]
+		c.pending[0] = nil
+		c.pending = c.pending[1:]
+		c.lock.Unlock()
+
+		resp, err := c.cli.Recv()
+		if err != nil {
+			c.lock.Lock()
+			c.fail(err)
+			cl.err = c.err
+			c.lock.Unlock()
+		} else {
+			cl.resp = resp
+		}
+		close(cl.done)
+	}
+}
@@ -0,0 +1,159 @@
+// Package mux goroutine safe multiplexed client
+//
+// 协程安全的多路复用连接，多个协程共享少量连接，请求在连接上排队发送，按顺序匹配响应
+package mux
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
+	"github.com/seefan/goerr"
+	"github.com/seefan/gossdb/v2/client"
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/ssdbclient"
+)
+
+// ErrClosed returned by the commands after the client is closed
+//
+// 连接关闭后执行命令返回的错误
+var ErrClosed = goerr.String("gossdb mux client is closed")
+
+// Client multiplexed client, goroutine safe. The commands of many goroutines are pipelined on a few connections,
+// SSDB replies are ordered per connection so the responses are matched in order. The broken connections are reopened on the next command.
+//
+// 多路复用连接，协程安全。多个协程的命令以流水线方式在少量连接上发送，ssdb 在同一连接上按顺序返回，响应按顺序与请求匹配。出错的连接在下次使用时重新打开。
+type Client struct {
+	cfg *conf.Config
+	//连接槽，轮流使用
+	slots []*slot
+	//下一个使用的连接槽
+	next uint32
+	//是否已关闭
+	closed int32
+	//类型化的连接
+	typed *client.Client
+	//The input parameter is converted to [] bytes, which by default is converted to json format
+	//将输入参数成[]byte，默认会转换成json格式
+	EncodingFunc func(v interface{}) []byte
+}
+
+// 一个连接槽，连接出错后重新打开
+type slot struct {
+	lock sync.Mutex
+	conn *conn
+}
+
+// New create a multiplexed client and open the connections
+//
+//	@param cfg config, the pool sizes are ignored
+//	@param size number of the connections, at least 1
+//	@return *Client
+//	@return error possible error, operation successfully returned nil
+//
+// 创建多路复用连接并打开 size 个连接
+func New(cfg *conf.Config, size int) (*Client, error) {
+	if size < 1 {
+		size = 1
+	}
+	c := &Client{
+		cfg:   cfg.Default(),
+		slots: make([]*slot, size),
+		EncodingFunc: func(v interface{}) []byte {
+			if bs, err := json.Marshal(v); err == nil {
+				return bs
+			}
+			return nil
+		},
+	}
+	for i := range c.slots {
+		c.slots[i] = &slot{}
+	}
+	for _, s := range c.slots {
+		if _, err := c.conn(s); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	c.typed = client.NewHandlerClient(func(ctx context.Context, cmd string, args ...interface{}) ([]string, error) {
+		return c.DoContext(ctx, append([]interface{}{cmd}, args...)...)
+	})
+	return c, nil
+}
+
+// 取连接槽中的连接，连接出错时重新打开
+func (c *Client) conn(s *slot) (*conn, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return nil, ErrClosed
+	}
+	if s.conn != nil && !s.conn.failed() {
+		return s.conn, nil
+	}
+	sc := ssdbclient.NewSSDBClient(c.cfg)
+	sc.EncodingFunc = func(v interface{}) []byte {
+		return c.EncodingFunc(v)
+	}
+	if err := sc.Start(); err != nil {
+		return nil, err
+	}
+	s.conn = newConn(sc)
+	return s.conn, nil
+}
+
+// Do execute a command, see client.Client.Do
+//
+//	@param args the command and the arguments
+//	@return []string response
+//	@return error possible error, operation successfully returned nil
+//
+// 执行一个命令
+func (c *Client) Do(args ...interface{}) ([]string, error) {
+	return c.DoContext(context.Background(), args...)
+}
+
+// DoContext execute a command, returns when the response is received or the context is done
+//
+//	@param ctx context
+//	@param args the command and the arguments
+//	@return []string response
+//	@return error possible error, operation successfully returned nil
+//
+// 执行一个命令，收到响应或上下文结束时返回
+func (c *Client) DoContext(ctx context.Context, args ...interface{}) ([]string, error) {
+	if len(args) == 0 {
+		return nil, goerr.String("command is empty")
+	}
+	s := c.slots[atomic.AddUint32(&c.next, 1)%uint32(len(c.slots))]
+	cn, err := c.conn(s)
+	if err != nil {
+		return nil, err
+	}
+	return cn.do(ctx, args)
+}
+
+// Client returns the typed client sharing the connections, it is goroutine safe, Use must be called before sharing it
+//
+//	@return *client.Client
+//
+// 返回共享这些连接的类型化连接，可以在多个协程中使用，不需要关闭。如需添加中间件，请在共享前调用 Use
+func (c *Client) Client() *client.Client {
+	return c.typed
+}
+
+// Close close all connections, the waiting commands return ErrClosed
+//
+// 关闭所有连接，等待中的命令返回 ErrClosed
+func (c *Client) Close() {
+	atomic.StoreInt32(&c.closed, 1)
+	for _, s := range c.slots {
+		s.lock.Lock()
+		if s.conn != nil {
+			s.conn.close(ErrClosed)
+			s.conn = nil
+		}
+		s.lock.Unlock()
+	}
+}
@@ -0,0 +1,157 @@
+package mux
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seefan/gossdb/v2/conf"
+)
+
+func TestClient(t *testing.T) {
+	c, err := New(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	cc := c.Client()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				key := fmt.Sprintf("mux:%d:%d", i, j)
+				if err := cc.Set(key, key); err != nil {
+					t.Error(err)
+					return
+				}
+				v, err := cc.Get(key)
+				if err != nil || v.String() != key {
+					t.Errorf("get %s: %q %v", key, v, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestClientContext(t *testing.T) {
+	c, err := New(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	skipWithoutSleep(t, c)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = c.DoContext(ctx, "sleep", 200); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	//取消的请求的响应被丢弃，后续的请求仍然匹配正确
+	if err = c.Client().Set("mux:ctx", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Client().Get("mux:ctx"); err != nil || v.String() != "v" {
+		t.Errorf("get after cancel: %q %v", v, err)
+	}
+	c.Close()
+	if _, err = c.Do("get", "mux:ctx"); err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestClientLarge(t *testing.T) {
+	const size = 1 << 20
+	l := serveLarge(t, size)
+	defer l.Close()
+	c, err := New(&conf.Config{
+		Host:             "127.0.0.1",
+		Port:             l.Addr().(*net.TCPAddr).Port,
+		ReadWriteTimeout: 5,
+		ReadBufferSize:   128,
+		WriteBufferSize:  128,
+	}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	//大的请求和大的响应同时在连接上，发送时不能阻塞接收
+	big := strings.Repeat("v", size)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				if resp, err := c.Do("set", "mux:large", big); err != nil || len(resp) != 2 || len(resp[1]) != size {
+					t.Error(len(resp), err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// 逐个读取请求并返回 size 字节的值的服务，写响应时不读取后面的请求
+func serveLarge(t *testing.T, size int) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := []byte("2\nok\n" + strconv.Itoa(size) + "\n" + strings.Repeat("v", size) + "\n\n")
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					for {
+						line, err := r.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if line = strings.TrimRight(line, "\r\n"); line == "" {
+							break
+						}
+						n, _ := strconv.Atoi(line)
+						if _, err = r.Discard(n + 1); err != nil {
+							return
+						}
+					}
+					if _, err := conn.Write(resp); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+// 超时的测试使用测试服务的 sleep 命令，真实的 ssdb 没有这个命令，跳过测试
+func skipWithoutSleep(t *testing.T, c *Client) {
+	t.Helper()
+	if resp, err := c.Do("sleep", 0); err != nil || len(resp) == 0 || resp[0] != "ok" {
+		t.Skip("the server does not support the sleep command of the test server")
+	}
+}
@@ -0,0 +1,125 @@
+package namespace
+
+import (
+	"github.com/seefan/gossdb/v2/client"
+)
+
+// HSet 设置 hashmap 中指定 key 对应的值内容.
+func (c *Client) HSet(setName string, key string, value interface{}) (err error) {
+	return c.client.HSet(c.prefix+setName, key, value)
+}
+
+// HGet 获取 hashmap 中指定 key 的值内容.
+func (c *Client) HGet(setName string, key string) (value client.Value, err error) {
+	return c.client.HGet(c.prefix+setName, key)
+}
+
+// HDel 删除 hashmap 中的指定 key，不能通过返回值来判断被删除的 key 是否存在.
+func (c *Client) HDel(setName string, key string) (err error) {
+	return c.client.HDel(c.prefix+setName, key)
+}
+
+// HExists 判断指定的 key 是否存在于 hashmap 中.
+func (c *Client) HExists(setName string, key string) (re bool, err error) {
+	return c.client.HExists(c.prefix+setName, key)
+}
+
+// HClear 删除 hashmap 中的所有 key
+func (c *Client) HClear(setName string) (err error) {
+	return c.client.HClear(c.prefix + setName)
+}
+
+// HScan 列出 hashmap 中处于区间 (key_start, key_end] 的 key-value 列表. ("", ""] 表示整个区间.
+func (c *Client) HScan(setName string, keyStart string, keyEnd string, limit int64, reverse ...bool) (map[string]client.Value, error) {
+	return c.client.HScan(c.prefix+setName, keyStart, keyEnd, limit, reverse...)
+}
+
+// HScanArray 列出 hashmap 中处于区间 (key_start, key_end] 的 key,value 列表. ("", ""] 表示整个区间.
+func (c *Client) HScanArray(setName string, keyStart string, keyEnd string, limit int64, reverse ...bool) ([]string, []client.Value, error) {
+	return c.client.HScanArray(c.prefix+setName, keyStart, keyEnd, limit, reverse...)
+}
+
+// HRScanArray 列出 hashmap 中处于区间 (key_start, key_end] 的 key,value 列表. ("", ""] 表示整个区间.
+func (c *Client) HRScanArray(setName string, keyStart string, keyEnd string, limit int64, reverse ...bool) ([]string, []client.Value, error) {
+	return c.client.HRScanArray(c.prefix+setName, keyStart, keyEnd, limit, reverse...)
+}
+
+// HRScan 列出 hashmap 中处于区间 (key_start, key_end] 的 key-value 列表. ("", ""] 表示整个区间.
+func (c *Client) HRScan(setName string, keyStart string, keyEnd string, limit int64) (map[string]client.Value, error) {
+	return c.client.HRScan(c.prefix+setName, keyStart, keyEnd, limit)
+}
+
+// MultiHSet 批量设置 hashmap 中的 key-value.
+func (c *Client) MultiHSet(setName string, kvs map[string]interface{}) (err error) {
+	return c.client.MultiHSet(c.prefix+setName, kvs)
+}
+
+// MultiHGet 批量获取 hashmap 中多个 key 对应的权重值.
+func (c *Client) MultiHGet(setName string, key ...string) (val map[string]client.Value, err error) {
+	return c.client.MultiHGet(c.prefix+setName, key...)
+}
+
+// MultiHGetSlice 批量获取 hashmap 中多个 key 对应的权重值.
+func (c *Client) MultiHGetSlice(setName string, key ...string) (keys []string, values []client.Value, err error) {
+	return c.client.MultiHGetSlice(c.prefix+setName, key...)
+}
+
+// MultiHGetArray 批量获取 hashmap 中多个 key 对应的权重值.（输入分片）
+func (c *Client) MultiHGetArray(setName string, key []string) (val map[string]client.Value, err error) {
+	return c.client.MultiHGetArray(c.prefix+setName, key)
+}
+
+// MultiHGetSliceArray 批量获取 hashmap 中多个 key 对应的权重值.（输入分片）
+func (c *Client) MultiHGetSliceArray(setName string, key []string) (keys []string, values []client.Value, err error) {
+	return c.client.MultiHGetSliceArray(c.prefix+setName, key)
+}
+
+// MultiHGetAll 批量获取 hashmap 中全部 对应的权重值.
+func (c *Client) MultiHGetAll(setName string) (val map[string]client.Value, err error) {
+	return c.client.MultiHGetAll(c.prefix + setName)
+}
+
+// MultiHGetAllSlice 批量获取 hashmap 中全部 对应的权重值.
+func (c *Client) MultiHGetAllSlice(setName string) (keys []string, values []client.Value, err error) {
+	return c.client.MultiHGetAllSlice(c.prefix + setName)
+}
+
+// MultiHDel 批量删除 hashmap 中的 key.
+func (c *Client) MultiHDel(setName string, key ...string) (err error) {
+	return c.client.MultiHDel(c.prefix+setName, key...)
+}
+
+// MultiHDelArray 批量删除 hashmap 中的 key.（输入分片）
+func (c *Client) MultiHDelArray(setName string, key []string) (err error) {
+	return c.client.MultiHDelArray(c.prefix+setName, key)
+}
+
+// HIncr 设置 hashmap 中指定 key 对应的值增加 num. 参数 num 可以为负数.
+func (c *Client) HIncr(setName string, key string, num int64) (val int64, err error) {
+	return c.client.HIncr(c.prefix+setName, key, num)
+}
+
+// HSize 返回 hashmap 中的元素个数.
+func (c *Client) HSize(setName string) (val int64, err error) {
+	return c.client.HSize(c.prefix + setName)
+}
+
+// HKeys 列出 hashmap 中处于区间 (keyStart, keyEnd] 的 key 列表.
+func (c *Client) HKeys(setName string, keyStart string, keyEnd string, limit int64) ([]string, error) {
+	return c.client.HKeys(c.prefix+setName, keyStart, keyEnd, limit)
+}
+
+// HGetAll 批量获取 hashmap 中全部 对应的权重值.
+func (c *Client) HGetAll(setName string) (val map[string]client.Value, err error) {
+	return c.client.HGetAll(c.prefix + setName)
+}
+
+// HList 列出名字处于区间 (name_start, name_end] 的 hashmap，只返回命名空间内的名字.
+func (c *Client) HList(nameStart, nameEnd string, limit int64) ([]string, error) {
+	nameStart, nameEnd = c.forward(nameStart, nameEnd)
+	re, err := c.client.HList(nameStart, nameEnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.stripSlice(re), nil
+}
@@ -0,0 +1,120 @@
+// Package namespace key namespace wrapper of client.Client
+//
+// 键的命名空间，所有的 key 和 hashmap、zset、queue 的名称都会自动加上前缀，列表和扫描只返回命名空间内的 key，并去掉前缀
+package namespace
+
+import (
+	"strings"
+
+	"github.com/seefan/gossdb/v2/client"
+)
+
+// Client namespaced client, not goroutine safe as client.Client
+//
+// 带命名空间的连接，与 client.Client 一样非协程安全
+type Client struct {
+	client *client.Client
+	prefix string
+}
+
+// New wrap the client with the namespace prefix
+//
+//	@param c the client, e.g. the Client of a pooled connection
+//	@param prefix the namespace prefix, e.g. "team:"
+//	@return *Client
+//
+// 使用命名空间前缀包装连接
+func New(c *client.Client, prefix string) *Client {
+	return &Client{
+		client: c,
+		prefix: prefix,
+	}
+}
+
+// Prefix returns the namespace prefix
+func (c *Client) Prefix() string {
+	return c.prefix
+}
+
+// Ping ping ssdb
+func (c *Client) Ping() bool {
+	return c.client.Ping()
+}
+
+// 命名空间的上界，所有带前缀的 key 都小于它，前缀为空或全为 0xff 时没有上界
+func (c *Client) upper() string {
+	bs := []byte(c.prefix)
+	for i := len(bs) - 1; i >= 0; i-- {
+		if bs[i] < 0xff {
+			bs[i]++
+			return string(bs[:i+1])
+		}
+	}
+	return ""
+}
+
+// 正向扫描的区间 (start, end]，空值表示命名空间的边界
+func (c *Client) forward(start, end string) (string, string) {
+	if start == "" {
+		start = c.prefix
+	} else {
+		start = c.prefix + start
+	}
+	if end == "" {
+		end = c.upper()
+	} else {
+		end = c.prefix + end
+	}
+	return start, end
+}
+
+// 反向扫描的区间 (end, start]，空值表示命名空间的边界
+func (c *Client) reverse(start, end string) (string, string) {
+	if start == "" {
+		start = c.upper()
+	} else {
+		start = c.prefix + start
+	}
+	if end == "" {
+		end = c.prefix
+	} else {
+		end = c.prefix + end
+	}
+	return start, end
+}
+
+// 去掉前缀，不在命名空间内的返回 false
+func (c *Client) strip(key string) (string, bool) {
+	if strings.HasPrefix(key, c.prefix) {
+		return key[len(c.prefix):], true
+	}
+	return "", false
+}
+
+func (c *Client) stripSlice(keys []string) []string {
+	re := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if s, ok := c.strip(k); ok {
+			re = append(re, s)
+		}
+	}
+	return re
+}
+
+func (c *Client) stripMap(kvs map[string]client.Value) map[string]client.Value {
+	re := make(map[string]client.Value, len(kvs))
+	for k, v := range kvs {
+		if s, ok := c.strip(k); ok {
+			re[s] = v
+		}
+	}
+	return re
+}
+
+func (c *Client) prefixSlice(keys []string) []string {
+	re := make([]string, len(keys))
+	for i, k := range keys {
+		re[i] = c.prefix + k
+	}
+	return re
+}
@@ -0,0 +1,72 @@
+package namespace
+
+import (
+	"testing"
+
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+func TestRange(t *testing.T) {
+	c := New(nil, "a:")
+	if s, e := c.forward("", ""); s != "a:" || e != "a;" {
+		t.Error(s, e)
+	}
+	if s, e := c.reverse("", "b"); s != "a;" || e != "a:b" {
+		t.Error(s, e)
+	}
+	if u := New(nil, "a\xff\xff").upper(); u != "b" {
+		t.Error(u)
+	}
+	if u := New(nil, "").upper(); u != "" {
+		t.Error(u)
+	}
+}
+
+func TestClient(t *testing.T) {
+	p := pool.NewConnectors(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	pc, err := p.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	a := New(&pc.Client, "ns_a:")
+	b := New(&pc.Client, "ns_b:")
+	if err = a.Set("k", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Set("k", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.HSet("h", "f", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := a.Get("k"); err != nil || v != "1" {
+		t.Error(v, err)
+	}
+	if v, err := pc.Get("ns_b:k"); err != nil || v != "2" {
+		t.Error(v, err)
+	}
+	keys, err := a.Keys("", "", 100)
+	if err != nil || len(keys) != 1 || keys[0] != "k" {
+		t.Error(keys, err)
+	}
+	kvs, err := b.MultiGet("k", "x")
+	if err != nil || len(kvs) != 1 || kvs["k"] != "2" {
+		t.Error(kvs, err)
+	}
+	names, err := a.HList("", "", 100)
+	if err != nil || len(names) != 1 || names[0] != "h" {
+		t.Error(names, err)
+	}
+	if names, err = b.HList("", "", 100); err != nil || len(names) != 0 {
+		t.Error(names, err)
+	}
+}
@@ -0,0 +1,140 @@
+package namespace
+
+import (
+	"github.com/seefan/gossdb/v2/client"
+)
+
+// QSize 返回队列的长度.
+func (c *Client) QSize(name string) (size int64, err error) {
+	return c.client.QSize(c.prefix + name)
+}
+
+// QClear 清空一个队列.
+func (c *Client) QClear(name string) (err error) {
+	return c.client.QClear(c.prefix + name)
+}
+
+// QPushFront 往队列的首部添加一个或者多个元素
+func (c *Client) QPushFront(name string, value ...interface{}) (size int64, err error) {
+	return c.client.QPushFront(c.prefix+name, value...)
+}
+
+// QPush 往队列的尾部添加一个或者多个元素
+func (c *Client) QPush(name string, value ...interface{}) (size int64, err error) {
+	return c.client.QPush(c.prefix+name, value...)
+}
+
+// QPushBack 往队列的尾部添加一个或者多个元素
+func (c *Client) QPushBack(name string, value ...interface{}) (size int64, err error) {
+	return c.client.QPushBack(c.prefix+name, value...)
+}
+
+// QPopFront 从队列首部弹出最后一个元素.
+func (c *Client) QPopFront(name string) (v client.Value, err error) {
+	return c.client.QPopFront(c.prefix + name)
+}
+
+// QPopBack 从队列尾部弹出最后一个元素.
+func (c *Client) QPopBack(name string) (v client.Value, err error) {
+	return c.client.QPopBack(c.prefix + name)
+}
+
+// QPop 从队列首部弹出最后一个元素.
+func (c *Client) QPop(name string, reverse ...bool) (v client.Value, err error) {
+	return c.client.QPop(c.prefix+name, reverse...)
+}
+
+// QPopFrontArray 从队列首部弹出最后多个元素.
+func (c *Client) QPopFrontArray(name string, size int64) (v []client.Value, err error) {
+	return c.client.QPopFrontArray(c.prefix+name, size)
+}
+
+// QPopBackArray 从队列尾部弹出最后多个元素.
+func (c *Client) QPopBackArray(name string, size int64) (v []client.Value, err error) {
+	return c.client.QPopBackArray(c.prefix+name, size)
+}
+
+// QPopArray 从队列首部弹出最后多个个元素.
+func (c *Client) QPopArray(name string, size int64, reverse ...bool) (v []client.Value, err error) {
+	return c.client.QPopArray(c.prefix+name, size, reverse...)
+}
+
+// QRange 返回下标处于区域 [offset, offset + limit] 的元素.
+func (c *Client) QRange(name string, offset int, limit int) (v []client.Value, err error) {
+	return c.client.QRange(c.prefix+name, offset, limit)
+}
+
+// QSlice 返回下标处于区域 [begin, end] 的元素. begin 和 end 可以是负数
+func (c *Client) QSlice(name string, begin int, end int) (v []client.Value, err error) {
+	return c.client.QSlice(c.prefix+name, begin, end)
+}
+
+// QTrim 从队列头部删除多个元素.
+func (c *Client) QTrim(name string, size int, reverse ...bool) (delSize int64, err error) {
+	return c.client.QTrim(c.prefix+name, size, reverse...)
+}
+
+// QTrimFront 从队列头部删除多个元素.
+func (c *Client) QTrimFront(name string, size int) (delSize int64, err error) {
+	return c.client.QTrimFront(c.prefix+name, size)
+}
+
+// QTrimBack 从队列尾部删除多个元素.
+func (c *Client) QTrimBack(name string, size int) (delSize int64, err error) {
+	return c.client.QTrimBack(c.prefix+name, size)
+}
+
+// QSet 更新位于 index 位置的元素. 如果超过现有的元素范围, 会返回错误.
+func (c *Client) QSet(key string, index int64, val interface{}) (err error) {
+	return c.client.QSet(c.prefix+key, index, val)
+}
+
+// QGet 返回指定位置的元素. 0 表示第一个元素, 1 是第二个 ... -1 是最后一个.
+func (c *Client) QGet(key string, index int64) (client.Value, error) {
+	return c.client.QGet(c.prefix+key, index)
+}
+
+// QFront 返回队列的第一个元素.
+func (c *Client) QFront(key string) (client.Value, error) {
+	return c.client.QFront(c.prefix + key)
+}
+
+// QBack 返回队列的最后一个元素.
+func (c *Client) QBack(key string) (client.Value, error) {
+	return c.client.QBack(c.prefix + key)
+}
+
+// QPushArray 往队列的尾部添加一个或者多个元素
+func (c *Client) QPushArray(name string, value []interface{}) (size int64, err error) {
+	return c.client.QPushArray(c.prefix+name, value)
+}
+
+// QPushBackArray 往队列的尾部添加一个或者多个元素
+func (c *Client) QPushBackArray(name string, value []interface{}) (size int64, err error) {
+	return c.client.QPushBackArray(c.prefix+name, value)
+}
+
+// QPushFrontArray 往队列的首部添加一个或者多个元素
+func (c *Client) QPushFrontArray(name string, value []interface{}) (size int64, err error) {
+	return c.client.QPushFrontArray(c.prefix+name, value)
+}
+
+// QList 列出名字处于区间 (name_start, name_end] 的 queue，只返回命名空间内的名字.
+func (c *Client) QList(nameStart, nameEnd string, limit int64) ([]string, error) {
+	nameStart, nameEnd = c.forward(nameStart, nameEnd)
+	re, err := c.client.QList(nameStart, nameEnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.stripSlice(re), nil
+}
+
+// QRList 列出名字处于区间 (name_start, name_end] 的 queue，反向顺序，只返回命名空间内的名字.
+func (c *Client) QRList(nameStart, nameEnd string, limit int64) ([]string, error) {
+	nameStart, nameEnd = c.reverse(nameStart, nameEnd)
+	re, err := c.client.QRList(nameStart, nameEnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.stripSlice(re), nil
+}
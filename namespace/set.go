@@ -0,0 +1,165 @@
+package namespace
+
+import (
+	"github.com/seefan/gossdb/v2/client"
+)
+
+// Set 设置指定 key 的值内容
+func (c *Client) Set(key string, val interface{}, ttl ...int64) (err error) {
+	return c.client.Set(c.prefix+key, val, ttl...)
+}
+
+// SetNX 当 key 不存在时, 设置指定 key 的值内容. 如果已存在, 则不设置.
+func (c *Client) SetNX(key string, val interface{}) (client.Value, error) {
+	return c.client.SetNX(c.prefix+key, val)
+}
+
+// Get 获取指定 key 的值内容
+func (c *Client) Get(key string) (client.Value, error) {
+	return c.client.Get(c.prefix + key)
+}
+
+// GetSet 更新 key 对应的 value, 并返回更新前的旧的 value.
+func (c *Client) GetSet(key string, val interface{}) (client.Value, error) {
+	return c.client.GetSet(c.prefix+key, val)
+}
+
+// Expire 设置过期
+func (c *Client) Expire(key string, ttl int64) (re bool, err error) {
+	return c.client.Expire(c.prefix+key, ttl)
+}
+
+// Exists 查询指定 key 是否存在
+func (c *Client) Exists(key string) (re bool, err error) {
+	return c.client.Exists(c.prefix + key)
+}
+
+// Del 删除指定 key
+func (c *Client) Del(key string) error {
+	return c.client.Del(c.prefix + key)
+}
+
+// TTL 返回 key(只针对 KV 类型) 的存活时间.
+func (c *Client) TTL(key string) (ttl int64, err error) {
+	return c.client.TTL(c.prefix + key)
+}
+
+// Incr 使 key 对应的值增加 num. 参数 num 可以为负数.
+func (c *Client) Incr(key string, num int64) (val int64, err error) {
+	return c.client.Incr(c.prefix+key, num)
+}
+
+// Setbit 设置字符串内指定位置的位值(BIT), 字符串的长度会自动扩展.
+func (c *Client) Setbit(key string, offset int64, bit int) (uint, error) {
+	return c.client.Setbit(c.prefix+key, offset, bit)
+}
+
+// Getbit 获取字符串内指定位置的位值(BIT).
+func (c *Client) Getbit(key string, offset int64) (uint, error) {
+	return c.client.Getbit(c.prefix+key, offset)
+}
+
+// BitCount 计算字符串的子串所包含的位值为 1 的个数. 若 start 是负数, 则从字符串末尾算起. 若 end 是负数, 则表示从字符串末尾算起(包含). 类似 Redis 的 bitcount
+func (c *Client) BitCount(key string, start int64, end int64) (int64, error) {
+	return c.client.BitCount(c.prefix+key, start, end)
+}
+
+// CountBit 计算字符串的子串所包含的位值为 1 的个数. 若 start 是负数, 则从字符串末尾算起. 若 size 是负数, 则表示从字符串末尾算起, 忽略掉那么多字节.
+func (c *Client) CountBit(key string, start int64, size int64) (int64, error) {
+	return c.client.CountBit(c.prefix+key, start, size)
+}
+
+// Substr 获取字符串的子串.
+func (c *Client) Substr(key string, start int64, size ...int64) (val string, err error) {
+	return c.client.Substr(c.prefix+key, start, size...)
+}
+
+// StrLen 计算字符串的长度(字节数).
+func (c *Client) StrLen(key string) (int64, error) {
+	return c.client.StrLen(c.prefix + key)
+}
+
+// MultiSet 批量设置一批 key-value.
+func (c *Client) MultiSet(kvs map[string]interface{}) (err error) {
+	re := make(map[string]interface{}, len(kvs))
+	for k, v := range kvs {
+		re[c.prefix+k] = v
+	}
+	return c.client.MultiSet(re)
+}
+
+// MultiGet 批量获取一批 key 对应的值内容.
+func (c *Client) MultiGet(key ...string) (val map[string]client.Value, err error) {
+	re, err := c.client.MultiGet(c.prefixSlice(key)...)
+	if err != nil {
+		return nil, err
+	}
+	return c.stripMap(re), nil
+}
+
+// MultiGetSlice 批量获取一批 key 对应的值内容.
+func (c *Client) MultiGetSlice(key ...string) (keys []string, values []client.Value, err error) {
+	keys, values, err = c.client.MultiGetSlice(c.prefixSlice(key)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, k := range keys {
+		keys[i], _ = c.strip(k)
+	}
+	return keys, values, nil
+}
+
+// MultiGetArray 批量获取一批 key 对应的值内容.
+func (c *Client) MultiGetArray(key []string) (val map[string]client.Value, err error) {
+	return c.MultiGet(key...)
+}
+
+// MultiGetSliceArray 批量获取一批 key 对应的值内容.
+func (c *Client) MultiGetSliceArray(key []string) (keys []string, values []client.Value, err error) {
+	return c.MultiGetSlice(key...)
+}
+
+// MultiDel 批量删除一批 key 和其对应的值内容.
+func (c *Client) MultiDel(key ...string) (err error) {
+	return c.client.MultiDel(c.prefixSlice(key)...)
+}
+
+// Keys 列出处于区间 (key_start, key_end] 的 key 列表，只返回命名空间内的 key.
+func (c *Client) Keys(keyStart, keyEnd string, limit int64) ([]string, error) {
+	keyStart, keyEnd = c.forward(keyStart, keyEnd)
+	re, err := c.client.Keys(keyStart, keyEnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.stripSlice(re), nil
+}
+
+// RKeys 列出处于区间 (key_start, key_end] 的 key 列表，反向顺序，只返回命名空间内的 key.
+func (c *Client) RKeys(keyStart, keyEnd string, limit int64) ([]string, error) {
+	keyStart, keyEnd = c.reverse(keyStart, keyEnd)
+	re, err := c.client.RKeys(keyStart, keyEnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.stripSlice(re), nil
+}
+
+// Scan 列出处于区间 (key_start, key_end] 的 key-value 列表，只返回命名空间内的 key.
+func (c *Client) Scan(keyStart, keyEnd string, limit int64) (map[string]client.Value, error) {
+	keyStart, keyEnd = c.forward(keyStart, keyEnd)
+	re, err := c.client.Scan(keyStart, keyEnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.stripMap(re), nil
+}
+
+// RScan 列出处于区间 (key_start, key_end] 的 key-value 列表，反向顺序，只返回命名空间内的 key.
+func (c *Client) RScan(keyStart, keyEnd string, limit int64) (map[string]client.Value, error) {
+	keyStart, keyEnd = c.reverse(keyStart, keyEnd)
+	re, err := c.client.RScan(keyStart, keyEnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.stripMap(re), nil
+}
@@ -0,0 +1,156 @@
+package namespace
+
+// ZSet 设置 zset 中指定 key 对应的权重值.
+func (c *Client) ZSet(setName string, key string, score int64) (err error) {
+	return c.client.ZSet(c.prefix+setName, key, score)
+}
+
+// ZGet 获取 zset 中指定 key 对应的权重值.
+func (c *Client) ZGet(setName string, key string) (score int64, err error) {
+	return c.client.ZGet(c.prefix+setName, key)
+}
+
+// ZDel 删除 zset 中指定 key
+func (c *Client) ZDel(setName string, key string) (err error) {
+	return c.client.ZDel(c.prefix+setName, key)
+}
+
+// ZExists 判断指定的 key 是否存在于 zset 中.
+func (c *Client) ZExists(setName string, key string) (re bool, err error) {
+	return c.client.ZExists(c.prefix+setName, key)
+}
+
+// ZCount 返回处于区间 [start,end] key 数量.
+func (c *Client) ZCount(setName string, start interface{}, end interface{}) (count int64, err error) {
+	return c.client.ZCount(c.prefix+setName, start, end)
+}
+
+// ZClear 删除 zset 中的所有 key.
+func (c *Client) ZClear(setName string) (err error) {
+	return c.client.ZClear(c.prefix + setName)
+}
+
+// ZScan 列出 zset 中处于区间 (key_start+score_start, score_end] 的 key-score 列表.
+func (c *Client) ZScan(setName string, keyStart string, scoreStart interface{}, scoreEnd interface{}, limit int64) (keys []string, scores []int64, err error) {
+	return c.client.ZScan(c.prefix+setName, keyStart, scoreStart, scoreEnd, limit)
+}
+
+// ZRScan 列出 zset 中的 key-score 列表, 反向顺序
+func (c *Client) ZRScan(setName string, keyStart string, scoreStart interface{}, scoreEnd interface{}, limit int64) (keys []string, scores []int64, err error) {
+	return c.client.ZRScan(c.prefix+setName, keyStart, scoreStart, scoreEnd, limit)
+}
+
+// MultiZSet 批量设置 zset 中的 key-score.
+func (c *Client) MultiZSet(setName string, kvs map[string]int64) (err error) {
+	return c.client.MultiZSet(c.prefix+setName, kvs)
+}
+
+// MultiZGet 批量获取 zset 中的 key-score.
+func (c *Client) MultiZGet(setName string, key ...string) (val map[string]int64, err error) {
+	return c.client.MultiZGet(c.prefix+setName, key...)
+}
+
+// MultiZGetSlice 批量获取 zset 中的 key-score.
+func (c *Client) MultiZGetSlice(setName string, key ...string) (keys []string, scores []int64, err error) {
+	return c.client.MultiZGetSlice(c.prefix+setName, key...)
+}
+
+// MultiZGetArray 批量获取 zset 中的 key-score.
+func (c *Client) MultiZGetArray(setName string, key []string) (val map[string]int64, err error) {
+	return c.client.MultiZGetArray(c.prefix+setName, key)
+}
+
+// MultiZgetSliceArray 批量获取 zset 中的 key-score.
+func (c *Client) MultiZgetSliceArray(setName string, key []string) (keys []string, scores []int64, err error) {
+	return c.client.MultiZgetSliceArray(c.prefix+setName, key)
+}
+
+// MultiZDel 批量删除 zset 中的 key-score.
+func (c *Client) MultiZDel(setName string, key ...string) (err error) {
+	return c.client.MultiZDel(c.prefix+setName, key...)
+}
+
+// ZIncr 使 zset 中的 key 对应的值增加 num. 参数 num 可以为负数.
+func (c *Client) ZIncr(setName string, key string, num int64) (int64, error) {
+	return c.client.ZIncr(c.prefix+setName, key, num)
+}
+
+// ZSize 返回 zset 中的元素个数.
+func (c *Client) ZSize(name string) (val int64, err error) {
+	return c.client.ZSize(c.prefix + name)
+}
+
+// ZKeys 列出 zset 中的 key 列表. 参见 zscan().
+func (c *Client) ZKeys(setName string, keyStart string, scoreStart interface{}, scoreEnd interface{}, limit int64) (keys []string, err error) {
+	return c.client.ZKeys(c.prefix+setName, keyStart, scoreStart, scoreEnd, limit)
+}
+
+// ZSum 返回 key 处于区间 [start,end] 的 score 的和.
+func (c *Client) ZSum(setName string, scoreStart interface{}, scoreEnd interface{}) (val int64, err error) {
+	return c.client.ZSum(c.prefix+setName, scoreStart, scoreEnd)
+}
+
+// ZAvg 返回 key 处于区间 [start,end] 的 score 的平均值.
+func (c *Client) ZAvg(setName string, scoreStart interface{}, scoreEnd interface{}) (val int64, err error) {
+	return c.client.ZAvg(c.prefix+setName, scoreStart, scoreEnd)
+}
+
+// ZRank 返回指定 key 在 zset 中的排序位置(排名), 排名从 0 开始. 注意! 本方法可能会非常慢! 请在离线环境中使用.
+func (c *Client) ZRank(setName string, key string) (val int64, err error) {
+	return c.client.ZRank(c.prefix+setName, key)
+}
+
+// ZRRank 返回指定 key 在 zset 中的倒序排名.注意! 本方法可能会非常慢! 请在离线环境中使用.
+func (c *Client) ZRRank(setName string, key string) (val int64, err error) {
+	return c.client.ZRRank(c.prefix+setName, key)
+}
+
+// ZRange 根据下标索引区间 [offset, offset + limit) 获取 key-score 对, 下标从 0 开始.注意! 本方法在 offset 越来越大时, 会越慢!
+func (c *Client) ZRange(setName string, offset int64, limit int64) (val map[string]int64, err error) {
+	return c.client.ZRange(c.prefix+setName, offset, limit)
+}
+
+// ZRangeSlice 根据下标索引区间 [offset, offset + limit) 获取 获取 key和score 数组对, 下标从 0 开始.注意! 本方法在 offset 越来越大时, 会越慢!
+func (c *Client) ZRangeSlice(setName string, offset int64, limit int64) (key []string, val []int64, err error) {
+	return c.client.ZRangeSlice(c.prefix+setName, offset, limit)
+}
+
+// ZRRange 根据下标索引区间 [offset, offset + limit) 获取 key-score 对, 反向顺序获取.注意! 本方法在 offset 越来越大时, 会越慢!
+func (c *Client) ZRRange(setName string, offset int64, limit int64) (val map[string]int64, err error) {
+	return c.client.ZRRange(c.prefix+setName, offset, limit)
+}
+
+// ZRRangeSlice 根据下标索引区间 [offset, offset + limit) 获取 key和score 数组对, 反向顺序获取.注意! 本方法在 offset 越来越大时, 会越慢!
+func (c *Client) ZRRangeSlice(setName string, offset int64, limit int64) (key []string, val []int64, err error) {
+	return c.client.ZRRangeSlice(c.prefix+setName, offset, limit)
+}
+
+// ZRemRangeByRank 删除位置处于区间 [start,end] 的元素.
+func (c *Client) ZRemRangeByRank(setName string, start int64, end int64) (err error) {
+	return c.client.ZRemRangeByRank(c.prefix+setName, start, end)
+}
+
+// ZRemRangeByScore 删除权重处于区间 [start,end] 的元素.
+func (c *Client) ZRemRangeByScore(setName string, start int64, end int64) (err error) {
+	return c.client.ZRemRangeByScore(c.prefix+setName, start, end)
+}
+
+// ZPopFront 从 zset 首部删除并返回 `limit` 个元素.
+func (c *Client) ZPopFront(setName string, limit int64) (val map[string]int64, err error) {
+	return c.client.ZPopFront(c.prefix+setName, limit)
+}
+
+// ZPopBack 从 zset 尾部删除并返回 `limit` 个元素.
+func (c *Client) ZPopBack(setName string, limit int64) (val map[string]int64, err error) {
+	return c.client.ZPopBack(c.prefix+setName, limit)
+}
+
+// ZList 列出名字处于区间 (name_start, name_end] 的 zset，只返回命名空间内的名字.
+func (c *Client) ZList(nameStart, nameEnd string, limit int64) ([]string, error) {
+	nameStart, nameEnd = c.forward(nameStart, nameEnd)
+	re, err := c.client.ZList(nameStart, nameEnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.stripSlice(re), nil
+}
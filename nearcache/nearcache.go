@@ -0,0 +1,281 @@
+// Package nearcache in-process cache in front of the connection pool
+//
+// 连接池前的进程内缓存，缓存 Get、HGet、MultiGet 的结果，本进程内的写操作会使缓存失效
+package nearcache
+
+import (
+	"time"
+
+	"github.com/seefan/gossdb/v2/client"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+const (
+	//kv 缓存 key 的前缀
+	kvPrefix = "k\x00"
+	//hashmap 缓存 key 的前缀
+	hashPrefix = "h\x00"
+)
+
+// Cache near cache, goroutine safe. Only the writes through this cache invalidate the cached values,
+// writes from other processes are visible after the ttl.
+//
+// 本地缓存，协程安全。只有通过本缓存的写操作会使缓存失效，其它进程的写操作要在缓存过期后才可见。
+type Cache struct {
+	pool  *pool.Connectors
+	store *store
+}
+
+// New create a near cache
+//
+//	@param p connection pool
+//	@param size maximum number of cached entries
+//	@param ttl cache time of each entry
+//	@param policy eviction policy, LRU or LFU
+//	@return *Cache
+//
+// 创建一个本地缓存
+func New(p *pool.Connectors, size int, ttl time.Duration, policy Policy) *Cache {
+	if size < 1 {
+		size = 1
+	}
+	return &Cache{
+		pool:  p,
+		store: newStore(size, ttl, policy),
+	}
+}
+
+// 取一个连接，使用完后需要关闭
+func (c *Cache) client() (*pool.Client, error) {
+	cc, err := c.pool.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	cc.AutoClose = false
+	return cc, nil
+}
+
+func hashKey(setName, key string) string {
+	return hashPrefix + setName + "\x00" + key
+}
+
+// Get returns the value of the key, see client.Client.Get
+//
+// 获取指定 key 的值，优先从本地缓存中获取
+func (c *Cache) Get(key string) (client.Value, error) {
+	if e, ok := c.store.get(kvPrefix + key); ok {
+		return client.Value(e.value), nil
+	}
+	version := c.store.current(kvPrefix + key)
+	cc, err := c.client()
+	if err != nil {
+		return "", err
+	}
+	defer cc.Close()
+	v, err := cc.Get(key)
+	if err != nil {
+		return "", err
+	}
+	c.store.set(version, kvPrefix+key, v.String(), !v.IsEmpty())
+	return v, nil
+}
+
+// HGet returns the value of the key in the hashmap, see client.Client.HGet
+//
+// 获取 hashmap 中指定 key 的值，优先从本地缓存中获取
+func (c *Cache) HGet(setName, key string) (client.Value, error) {
+	k := hashKey(setName, key)
+	if e, ok := c.store.get(k); ok {
+		return client.Value(e.value), nil
+	}
+	version := c.store.current(k)
+	cc, err := c.client()
+	if err != nil {
+		return "", err
+	}
+	defer cc.Close()
+	v, err := cc.HGet(setName, key)
+	if err != nil {
+		return "", err
+	}
+	c.store.set(version, k, v.String(), !v.IsEmpty())
+	return v, nil
+}
+
+// MultiGet returns the values of the keys, see client.Client.MultiGet. Only the missing keys are fetched from ssdb.
+//
+// 批量获取值，只有本地缓存中没有的 key 才从 ssdb 中获取
+func (c *Cache) MultiGet(key ...string) (map[string]client.Value, error) {
+	val := make(map[string]client.Value, len(key))
+	var missing []string
+	for _, k := range key {
+		if e, ok := c.store.get(kvPrefix + k); ok {
+			if e.exists {
+				val[k] = client.Value(e.value)
+			}
+		} else {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) == 0 {
+		return val, nil
+	}
+	versions := make([]int64, len(missing))
+	for i, k := range missing {
+		versions[i] = c.store.current(kvPrefix + k)
+	}
+	cc, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	defer cc.Close()
+	re, err := cc.MultiGet(missing...)
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range missing {
+		v, ok := re[k]
+		if ok {
+			val[k] = v
+		}
+		c.store.set(versions[i], kvPrefix+k, v.String(), ok)
+	}
+	return val, nil
+}
+
+// Set set the value of the key and invalidate the cached value, see client.Client.Set.
+// The value cached later expires no later than the ttl.
+//
+// 设置指定 key 的值，并使本地缓存失效。之后缓存的值不会晚于 ttl 过期。
+func (c *Cache) Set(key string, val interface{}, ttl ...int64) error {
+	defer c.store.invalidate(kvPrefix + key)
+	cc, err := c.client()
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	if err = cc.Set(key, val, ttl...); err != nil {
+		return err
+	}
+	var expire time.Duration
+	if len(ttl) > 0 {
+		expire = time.Duration(ttl[0]) * time.Second
+	}
+	c.store.expire(kvPrefix+key, expire)
+	return nil
+}
+
+// Del delete the key and invalidate the cached value, see client.Client.Del
+//
+// 删除指定的 key，并使本地缓存失效
+func (c *Cache) Del(key string) error {
+	defer c.store.invalidate(kvPrefix + key)
+	cc, err := c.client()
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	if err = cc.Del(key); err != nil {
+		return err
+	}
+	c.store.expire(kvPrefix+key, 0)
+	return nil
+}
+
+// MultiDel delete the keys and invalidate the cached values, see client.Client.MultiDel
+//
+// 批量删除 key，并使本地缓存失效
+func (c *Cache) MultiDel(key ...string) error {
+	keys := make([]string, len(key))
+	for i, k := range key {
+		keys[i] = kvPrefix + k
+	}
+	defer c.store.invalidate(keys...)
+	cc, err := c.client()
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	if err = cc.MultiDel(key...); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		c.store.expire(k, 0)
+	}
+	return nil
+}
+
+// HSet set the value of the key in the hashmap and invalidate the cached value, see client.Client.HSet
+//
+// 设置 hashmap 中指定 key 的值，并使本地缓存失效
+func (c *Cache) HSet(setName, key string, value interface{}) error {
+	defer c.store.invalidate(hashKey(setName, key))
+	cc, err := c.client()
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	return cc.HSet(setName, key, value)
+}
+
+// HDel delete the key in the hashmap and invalidate the cached value, see client.Client.HDel
+//
+// 删除 hashmap 中指定的 key，并使本地缓存失效
+func (c *Cache) HDel(setName, key string) error {
+	defer c.store.invalidate(hashKey(setName, key))
+	cc, err := c.client()
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	return cc.HDel(setName, key)
+}
+
+// HClear delete all keys in the hashmap and invalidate the cached values, see client.Client.HClear
+//
+// 删除 hashmap 中所有的 key，并使本地缓存失效
+func (c *Cache) HClear(setName string) error {
+	defer c.store.invalidatePrefix(hashPrefix + setName + "\x00")
+	cc, err := c.client()
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	return cc.HClear(setName)
+}
+
+// Invalidate remove the cached value of the key, used when the key is modified in other ways
+//
+// 使指定 key 的本地缓存失效，用于通过其它方式修改了 key 的情况
+func (c *Cache) Invalidate(key ...string) {
+	keys := make([]string, len(key))
+	for i, k := range key {
+		keys[i] = kvPrefix + k
+	}
+	c.store.invalidate(keys...)
+}
+
+// InvalidateHash remove the cached value of the key in the hashmap
+//
+// 使 hashmap 中指定 key 的本地缓存失效
+func (c *Cache) InvalidateHash(setName string, key ...string) {
+	keys := make([]string, len(key))
+	for i, k := range key {
+		keys[i] = hashKey(setName, k)
+	}
+	c.store.invalidate(keys...)
+}
+
+// Purge remove all cached values
+//
+// 清空本地缓存
+func (c *Cache) Purge() {
+	c.store.purge()
+}
+
+// Stats returns the cache statistics
+//
+// 返回缓存的统计信息
+func (c *Cache) Stats() Stats {
+	return c.store.statistics()
+}
@@ -0,0 +1,112 @@
+package nearcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+func TestStoreLRU(t *testing.T) {
+	s := newStore(2, time.Minute, LRU)
+	s.set(s.current("a"), "a", "1", true)
+	s.set(s.current("b"), "b", "2", true)
+	s.get("a")
+	s.set(s.current("c"), "c", "3", true)
+	if _, ok := s.get("b"); ok {
+		t.Error("b is not evicted")
+	}
+	if _, ok := s.get("a"); !ok {
+		t.Error("a is evicted")
+	}
+	if st := s.statistics(); st.Evictions != 1 || st.Size != 2 {
+		t.Errorf("stats error %+v", st)
+	}
+}
+
+func TestStoreLFU(t *testing.T) {
+	s := newStore(2, time.Minute, LFU)
+	s.set(s.current("a"), "a", "1", true)
+	s.set(s.current("b"), "b", "2", true)
+	s.get("a")
+	s.get("a")
+	s.get("b")
+	s.set(s.current("c"), "c", "3", true)
+	if _, ok := s.get("b"); ok {
+		t.Error("b is not evicted")
+	}
+	if _, ok := s.get("a"); !ok {
+		t.Error("a is evicted")
+	}
+}
+
+func TestStoreExpire(t *testing.T) {
+	s := newStore(2, time.Millisecond, LRU)
+	s.set(s.current("a"), "a", "1", true)
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := s.get("a"); ok {
+		t.Error("a is not expired")
+	}
+	version := s.current("b")
+	s.invalidate("b")
+	s.set(version, "b", "2", true)
+	if _, ok := s.get("b"); ok {
+		t.Error("b is cached after invalidation")
+	}
+	//其它分片的失效不影响加载中的值
+	var other string
+	for i := 0; other == ""; i++ {
+		if k := string(rune('c' + i)); shard(k) != shard("b") {
+			other = k
+		}
+	}
+	version = s.current("b")
+	s.invalidate(other)
+	s.set(version, "b", "2", true)
+	if _, ok := s.get("b"); !ok {
+		t.Error("b is dropped by the invalidation of", other)
+	}
+	//缓存的值不晚于 ssdb 中的过期时间
+	s = newStore(2, time.Minute, LRU)
+	s.expire("a", time.Millisecond)
+	s.set(s.current("a"), "a", "1", true)
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := s.get("a"); ok {
+		t.Error("a outlives the ttl in ssdb")
+	}
+}
+
+func TestCache(t *testing.T) {
+	p := pool.NewConnectors(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	c := New(p, 100, time.Minute, LRU)
+	if err := c.Set("near:a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if v, err := c.Get("near:a"); err != nil || v != "1" {
+			t.Error(v, err)
+		}
+	}
+	if err := c.Set("near:a", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get("near:a"); err != nil || v != "2" {
+		t.Error(v, err)
+	}
+	_ = c.Del("near:b")
+	re, err := c.MultiGet("near:a", "near:b")
+	if err != nil || len(re) != 1 || re["near:a"] != "2" {
+		t.Error(re, err)
+	}
+	if st := c.Stats(); st.Hits != 3 || st.Invalidations != 1 {
+		t.Errorf("stats error %+v", st)
+	}
+}
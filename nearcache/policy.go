@@ -0,0 +1,140 @@
+package nearcache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// Policy eviction policy
+//
+// 缓存满时的淘汰策略
+type Policy int
+
+const (
+	//LRU evict the least recently used entry
+	//淘汰最近最少使用的
+	LRU Policy = iota
+	//LFU evict the least frequently used entry
+	//淘汰使用频率最低的
+	LFU
+)
+
+// 缓存项
+type entry struct {
+	key string
+	//值
+	value string
+	//值在 ssdb 中是否存在
+	exists bool
+	//过期时间，纳秒
+	expireAt int64
+	//lru 中的位置
+	elem *list.Element
+	//lfu 中的使用次数、访问序号和位置
+	freq  int64
+	tick  int64
+	index int
+}
+
+// 淘汰策略的接口，由 Cache 加锁调用
+type evictor interface {
+	add(e *entry)
+	touch(e *entry)
+	remove(e *entry)
+	evict() *entry
+}
+
+func newEvictor(p Policy) evictor {
+	if p == LFU {
+		return &lfu{}
+	}
+	return &lru{list: list.New()}
+}
+
+// lru 最近使用的在链表头部
+type lru struct {
+	list *list.List
+}
+
+func (l *lru) add(e *entry) {
+	e.elem = l.list.PushFront(e)
+}
+
+func (l *lru) touch(e *entry) {
+	l.list.MoveToFront(e.elem)
+}
+
+func (l *lru) remove(e *entry) {
+	l.list.Remove(e.elem)
+}
+
+func (l *lru) evict() *entry {
+	if el := l.list.Back(); el != nil {
+		e := el.Value.(*entry)
+		l.list.Remove(el)
+		return e
+	}
+	return nil
+}
+
+// lfu 按使用次数排序的小顶堆，次数相同的先淘汰较早访问的
+type lfu struct {
+	entries []*entry
+	tick    int64
+}
+
+func (l *lfu) Len() int {
+	return len(l.entries)
+}
+
+func (l *lfu) Less(i, j int) bool {
+	a, b := l.entries[i], l.entries[j]
+	if a.freq == b.freq {
+		return a.tick < b.tick
+	}
+	return a.freq < b.freq
+}
+
+func (l *lfu) Swap(i, j int) {
+	l.entries[i], l.entries[j] = l.entries[j], l.entries[i]
+	l.entries[i].index = i
+	l.entries[j].index = j
+}
+
+func (l *lfu) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(l.entries)
+	l.entries = append(l.entries, e)
+}
+
+func (l *lfu) Pop() interface{} {
+	n := len(l.entries) - 1
+	e := l.entries[n]
+	l.entries[n] = nil
+	l.entries = l.entries[:n]
+	return e
+}
+
+func (l *lfu) add(e *entry) {
+	l.tick++
+	e.freq, e.tick = 1, l.tick
+	heap.Push(l, e)
+}
+
+func (l *lfu) touch(e *entry) {
+	l.tick++
+	e.freq++
+	e.tick = l.tick
+	heap.Fix(l, e.index)
+}
+
+func (l *lfu) remove(e *entry) {
+	heap.Remove(l, e.index)
+}
+
+func (l *lfu) evict() *entry {
+	if len(l.entries) == 0 {
+		return nil
+	}
+	return heap.Pop(l).(*entry)
+}
@@ -0,0 +1,200 @@
+package nearcache
+
+import (
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 失效版本号的分片数
+const versionShards = 64
+
+// Stats cache statistics
+//
+// 缓存的统计信息
+type Stats struct {
+	//命中次数
+	Hits int64
+	//未命中次数
+	Misses int64
+	//因容量淘汰的个数
+	Evictions int64
+	//因过期删除的个数
+	Expirations int64
+	//因写入失效的个数
+	Invalidations int64
+	//当前缓存的个数
+	Size int
+}
+
+// 带容量和过期时间的本地存储
+type store struct {
+	lock    sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*entry
+	evictor evictor
+	//按 key 分片的失效版本号，加载期间同一分片发生失效的值不写入缓存
+	versions [versionShards]int64
+	//通过本缓存设置了 ttl 的 key 在 ssdb 中的过期时间，纳秒，缓存的值不能比它更晚过期
+	deadlines map[string]int64
+	//上次清理过期时间后剩余的个数
+	deadlineSwept int
+	stats         Stats
+}
+
+func newStore(size int, ttl time.Duration, policy Policy) *store {
+	return &store{
+		size:      size,
+		ttl:       ttl,
+		entries:   make(map[string]*entry, size),
+		evictor:   newEvictor(policy),
+		deadlines: make(map[string]int64),
+	}
+}
+
+// key 所在的版本分片，hashmap 的 key 按 hashmap 名称分片，清空 hashmap 时只影响一个分片
+func shard(key string) int {
+	if strings.HasPrefix(key, hashPrefix) {
+		if i := strings.IndexByte(key[len(hashPrefix):], 0); i >= 0 {
+			key = key[:len(hashPrefix)+i]
+		}
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % versionShards)
+}
+
+// 获取缓存的值，过期的值会被删除
+func (s *store) get(key string) (e entry, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ce, ok := s.entries[key]
+	if ok && ce.expireAt <= time.Now().UnixNano() {
+		s.remove(ce)
+		s.stats.Expirations++
+		ok = false
+	}
+	if !ok {
+		s.stats.Misses++
+		return
+	}
+	s.stats.Hits++
+	s.evictor.touch(ce)
+	return *ce, true
+}
+
+// key 当前的失效版本号，加载前获取
+func (s *store) current(key string) int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.versions[shard(key)]
+}
+
+// 写入缓存，如果从 version 之后 key 所在的分片发生过失效就放弃写入。值的过期时间不超过 key 在 ssdb 中的过期时间。
+func (s *store) set(version int64, key, value string, exists bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if version != s.versions[shard(key)] {
+		return
+	}
+	now := time.Now().UnixNano()
+	expireAt := now + int64(s.ttl)
+	if deadline, ok := s.deadlines[key]; ok {
+		if deadline <= now {
+			//ssdb 中已过期
+			delete(s.deadlines, key)
+		} else if deadline < expireAt {
+			expireAt = deadline
+		}
+	}
+	if e, ok := s.entries[key]; ok {
+		e.value, e.exists, e.expireAt = value, exists, expireAt
+		s.evictor.touch(e)
+		return
+	}
+	for len(s.entries) >= s.size {
+		e := s.evictor.evict()
+		if e == nil {
+			break
+		}
+		delete(s.entries, e.key)
+		s.stats.Evictions++
+	}
+	e := &entry{key: key, value: value, exists: exists, expireAt: expireAt}
+	s.entries[key] = e
+	s.evictor.add(e)
+}
+
+// 记录 key 在 ssdb 中的过期时间，ttl 为 0 时表示不过期
+func (s *store) expire(key string, ttl time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if ttl <= 0 {
+		delete(s.deadlines, key)
+		return
+	}
+	now := time.Now().UnixNano()
+	s.deadlines[key] = now + int64(ttl)
+	if len(s.deadlines) >= 2*s.deadlineSwept+s.size {
+		//过期时间的个数翻倍时清理已过期的，清理的开销分摊到每次写入
+		for k, deadline := range s.deadlines {
+			if deadline <= now {
+				delete(s.deadlines, k)
+			}
+		}
+		s.deadlineSwept = len(s.deadlines)
+	}
+}
+
+// 使指定的 key 失效
+func (s *store) invalidate(keys ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, key := range keys {
+		s.versions[shard(key)]++
+		if e, ok := s.entries[key]; ok {
+			s.remove(e)
+			s.stats.Invalidations++
+		}
+	}
+}
+
+// 使指定前缀的 key 失效
+func (s *store) invalidatePrefix(prefix string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.versions[shard(prefix)]++
+	for key, e := range s.entries {
+		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
+			s.remove(e)
+			s.stats.Invalidations++
+		}
+	}
+}
+
+// 清空缓存
+func (s *store) purge() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := range s.versions {
+		s.versions[i]++
+	}
+	for _, e := range s.entries {
+		s.remove(e)
+	}
+}
+
+func (s *store) remove(e *entry) {
+	delete(s.entries, e.key)
+	s.evictor.remove(e)
+}
+
+func (s *store) statistics() Stats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := s.stats
+	st.Size = len(s.entries)
+	return st
+}
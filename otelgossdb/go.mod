@@ -0,0 +1,17 @@
+module github.com/seefan/gossdb/v2/otelgossdb
+
+go 1.21
+
+require (
+	github.com/seefan/gossdb/v2 v2.0.0
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/seefan/goerr v1.1.2 // indirect
+)
+
+replace github.com/seefan/gossdb/v2 => ../
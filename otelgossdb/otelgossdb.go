@@ -0,0 +1,102 @@
+// Package otelgossdb OpenTelemetry tracing of the connection pool and the ssdb commands
+//
+// 连接池和 ssdb 命令的 OpenTelemetry 追踪。每个命令和每次获取连接都会创建一个 span，
+// 父级 span 来自 Connectors.NewClientContext 或 SetContext 传入的上下文。
+package otelgossdb
+
+import (
+	"fmt"
+
+	"github.com/seefan/gossdb/v2/pool"
+	"github.com/seefan/gossdb/v2/ssdbclient"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/seefan/gossdb/v2/otelgossdb"
+
+// Tracer an observer of the connection pool which creates spans
+//
+// 创建 span 的连接池观察者
+type Tracer struct {
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+}
+
+// New create a tracer
+//
+//	@param tp tracer provider, the global provider is used if nil
+//	@param attrs additional attributes of all spans, e.g. the server address
+//	@return *Tracer
+//
+// 创建一个追踪器，tp 为空时使用全局的 TracerProvider
+func New(tp trace.TracerProvider, attrs ...attribute.KeyValue) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Tracer{
+		tracer: tp.Tracer(instrumentationName),
+		attrs:  append([]attribute.KeyValue{attribute.String("db.system", "ssdb")}, attrs...),
+	}
+}
+
+// Instrument add the tracer to the connection pool, it must be called before Start
+//
+//	@param c connection pool
+//	@param tp tracer provider, the global provider is used if nil
+//	@param attrs additional attributes of all spans
+//	@return *Tracer
+//
+// 为连接池添加追踪，必须在 Start 之前调用
+func Instrument(c *pool.Connectors, tp trace.TracerProvider, attrs ...attribute.KeyValue) *Tracer {
+	t := New(tp, attrs...)
+	c.AddObserver(t)
+	return t
+}
+
+// ObserveAcquire create a span of the acquisition
+func (t *Tracer) ObserveAcquire(stat *pool.AcquireStat) {
+	_, span := t.tracer.Start(stat.Ctx, "gossdb.acquire",
+		trace.WithTimestamp(stat.Start),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(t.attrs...),
+		trace.WithAttributes(attribute.Bool("gossdb.pool.waited", stat.Waited)),
+	)
+	if stat.Err != nil {
+		span.RecordError(stat.Err)
+		span.SetStatus(codes.Error, stat.Err.Error())
+	}
+	span.End(trace.WithTimestamp(stat.Start.Add(stat.Duration)))
+}
+
+// ObserveCommand create a span of the command
+func (t *Tracer) ObserveCommand(stat *ssdbclient.Stat) {
+	cmd := stat.Command()
+	attrs := []attribute.KeyValue{
+		attribute.String("db.operation", cmd),
+		attribute.Int64("gossdb.bytes_sent", stat.BytesSent),
+		attribute.Int64("gossdb.bytes_received", stat.BytesReceived),
+		attribute.Int("gossdb.attempts", stat.Attempts),
+	}
+	if len(stat.Args) > 1 {
+		attrs = append(attrs, attribute.String("gossdb.key", fmt.Sprint(stat.Args[1])))
+	}
+	if len(stat.Resp) > 0 {
+		attrs = append(attrs, attribute.String("gossdb.status", stat.Resp[0]))
+	}
+	_, span := t.tracer.Start(stat.Ctx, "ssdb "+cmd,
+		trace.WithTimestamp(stat.Start),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(t.attrs...),
+		trace.WithAttributes(attrs...),
+	)
+	if stat.Err != nil {
+		span.RecordError(stat.Err)
+		span.SetStatus(codes.Error, stat.ErrKind)
+	} else if len(stat.Resp) > 0 && stat.Resp[0] != "ok" && stat.Resp[0] != "not_found" {
+		span.SetStatus(codes.Error, stat.Resp[0])
+	}
+	span.End(trace.WithTimestamp(stat.Start.Add(stat.Duration)))
+}
@@ -0,0 +1,63 @@
+package otelgossdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/pool"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 记录 span 名称和父级的 TracerProvider
+type recorder struct {
+	trace.TracerProvider
+	lock  sync.Mutex
+	spans []string
+}
+
+func (r *recorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return r
+}
+
+func (r *recorder) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if p, ok := ctx.Value(parentKey{}).(string); ok {
+		name = p + "/" + name
+	}
+	r.spans = append(r.spans, name)
+	return ctx, trace.SpanFromContext(ctx)
+}
+
+type parentKey struct{}
+
+func TestInstrument(t *testing.T) {
+	p := pool.NewConnectors(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	})
+	r := &recorder{}
+	Instrument(p, r)
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ctx := context.WithValue(context.Background(), parentKey{}, "request")
+	c, err := p.NewClientContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = c.Get("a")
+	c.Close()
+	want := []string{"request/gossdb.acquire", "request/ssdb get"}
+	if len(r.spans) != len(want) {
+		t.Fatalf("spans are %v", r.spans)
+	}
+	for i := range want {
+		if r.spans[i] != want[i] {
+			t.Errorf("span %d is %s, want %s", i, r.spans[i], want[i])
+		}
+	}
+}
@@ -10,4 +10,5 @@ type Avaliable interface {
 	Pop() int
 	Put(int) int
 	IsEmpty() bool
+	Available() int
 }
@@ -0,0 +1,297 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/seefan/gossdb/v2/client"
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/metrics"
+	"github.com/seefan/gossdb/v2/ssdbclient"
+)
+
+// BreakerState state of the circuit breaker
+//
+// 熔断器的状态
+type BreakerState int32
+
+const (
+	//BreakerClosed the commands pass through
+	//关闭，正常执行
+	BreakerClosed BreakerState = iota
+	//BreakerOpen the acquisitions fail fast
+	//打开，获取连接直接失败
+	BreakerOpen
+	//BreakerHalfOpen probing the server with Ping, the acquisitions still fail fast
+	//半开，正在用 Ping 探测服务器，获取连接仍然直接失败
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int32(s))
+}
+
+// ErrCircuitOpen matches the *CircuitOpenError with errors.Is
+//
+// 可以用 errors.Is 判断是否为熔断错误
+var ErrCircuitOpen = errors.New("gossdb circuit breaker is open")
+
+// CircuitOpenError returned when acquiring a connection while the circuit breaker is not closed
+//
+// 熔断器没有关闭时获取连接返回的错误
+type CircuitOpenError struct {
+	//state of the breaker
+	//熔断器的状态
+	State BreakerState
+	//time of the next probe
+	//下次探测的时间
+	RetryAt time.Time
+	//the failure that tripped the breaker
+	//触发熔断的错误
+	Cause error
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("gossdb circuit breaker is %s, retry at %s: %v", e.State, e.RetryAt.Format(time.RFC3339), e.Cause)
+}
+
+// Is reports whether target is ErrCircuitOpen
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// Unwrap returns the failure that tripped the breaker
+func (e *CircuitOpenError) Unwrap() error {
+	return e.Cause
+}
+
+// 熔断器，连续失败或窗口内错误率超过阈值时打开，打开一段时间后用 Ping 探测，成功后关闭
+type breaker struct {
+	lock sync.Mutex
+	//当前状态，快速路径无锁读取
+	state int32
+	//连续失败次数
+	failures int
+	//窗口内的请求数
+	requests int
+	//窗口内的失败数
+	errors int
+	//窗口开始时间
+	windowStart time.Time
+	//打开的时间
+	openedAt time.Time
+	//触发熔断的错误
+	lastErr error
+	//打开的次数
+	trips *metrics.Counter
+
+	//连续失败阈值，0 不启用
+	maxFailures int
+	//错误率阈值，百分比，0 不启用
+	errorRate int
+	//计算错误率的最少请求数
+	minRequests int
+	//统计窗口
+	window time.Duration
+	//打开状态持续的时间
+	openTime time.Duration
+	//探测函数
+	probe func() error
+	//状态变化回调
+	onChange func(from, to BreakerState, err error)
+	//连接池的后台协程，关闭连接池时等待探测结束
+	background *sync.WaitGroup
+}
+
+func newBreaker(cfg *conf.Config) *breaker {
+	return &breaker{
+		trips:       &metrics.Counter{},
+		maxFailures: cfg.BreakerFailures,
+		errorRate:   cfg.BreakerErrorRate,
+		minRequests: cfg.BreakerMinRequests,
+		window:      time.Duration(cfg.BreakerWindowSecond) * time.Second,
+		openTime:    time.Duration(cfg.BreakerOpenSecond) * time.Second,
+	}
+}
+
+// 是否启用
+func (b *breaker) enabled() bool {
+	return b.maxFailures > 0 || b.errorRate > 0
+}
+
+// 当前状态
+func (b *breaker) State() BreakerState {
+	return BreakerState(atomic.LoadInt32(&b.state))
+}
+
+// 是否允许获取连接，在调用者的协程中执行，不开始探测
+func (b *breaker) allow() error {
+	if BreakerState(atomic.LoadInt32(&b.state)) == BreakerClosed {
+		return nil
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if s := BreakerState(b.state); s != BreakerClosed {
+		return &CircuitOpenError{State: s, RetryAt: b.openedAt.Add(b.openTime), Cause: b.lastErr}
+	}
+	return nil
+}
+
+// 定时检查，打开时间到期后转为半开并在后台探测。只由健康检查协程调用，
+// 它本身计入 background，探测协程的 Add 不会和关闭连接池时的 Wait 竞争
+func (b *breaker) tick(now time.Time) {
+	b.lock.Lock()
+	if BreakerState(b.state) != BreakerOpen || now.Sub(b.openedAt) < b.openTime {
+		b.lock.Unlock()
+		return
+	}
+	b.set(BreakerHalfOpen)
+	b.lock.Unlock()
+	b.changed(BreakerOpen, BreakerHalfOpen, nil)
+	if b.background != nil {
+		b.background.Add(1)
+	}
+	go func() {
+		if b.background != nil {
+			defer b.background.Done()
+		}
+		b.halfOpen()
+	}()
+}
+
+// 半开状态下探测服务器
+func (b *breaker) halfOpen() {
+	err := b.probe()
+	b.lock.Lock()
+	if BreakerState(b.state) != BreakerHalfOpen {
+		b.lock.Unlock()
+		return
+	}
+	to := BreakerClosed
+	if err != nil {
+		to = BreakerOpen
+		b.openedAt = time.Now()
+		b.lastErr = err
+	} else {
+		b.reset(time.Now())
+		b.lastErr = nil
+	}
+	b.set(to)
+	b.lock.Unlock()
+	b.changed(BreakerHalfOpen, to, err)
+}
+
+// 记录一次执行结果，只在关闭状态下统计
+func (b *breaker) record(err error, now time.Time) {
+	if !b.enabled() || BreakerState(atomic.LoadInt32(&b.state)) != BreakerClosed {
+		return
+	}
+	b.lock.Lock()
+	if BreakerState(b.state) != BreakerClosed {
+		b.lock.Unlock()
+		return
+	}
+	if now.Sub(b.windowStart) >= b.window {
+		b.reset(now)
+	}
+	b.requests++
+	if err == nil {
+		b.failures = 0
+		b.lock.Unlock()
+		return
+	}
+	b.failures++
+	b.errors++
+	if (b.maxFailures > 0 && b.failures >= b.maxFailures) ||
+		(b.errorRate > 0 && b.requests >= b.minRequests && b.errors*100 >= b.errorRate*b.requests) {
+		b.openedAt = now
+		b.lastErr = err
+		b.set(BreakerOpen)
+		b.trips.Inc()
+		b.lock.Unlock()
+		b.changed(BreakerClosed, BreakerOpen, err)
+		return
+	}
+	b.lock.Unlock()
+}
+
+// 重置统计窗口
+func (b *breaker) reset(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.errors = 0
+	b.failures = 0
+}
+
+func (b *breaker) set(s BreakerState) {
+	atomic.StoreInt32(&b.state, int32(s))
+}
+
+func (b *breaker) changed(from, to BreakerState, err error) {
+	if b.onChange != nil {
+		b.onChange(from, to, err)
+	}
+}
+
+// OnBreakerChange add a callback of the circuit breaker state changes, it must be called before Start
+//
+//	@param fn callback, err is the failure that tripped the breaker or the probe error
+//
+// 添加熔断器状态变化的回调，必须在 Start 之前调用
+func (c *Connectors) OnBreakerChange(fn func(from, to BreakerState, err error)) {
+	c.breakerCallbacks = append(c.breakerCallbacks, fn)
+}
+
+// BreakerState returns the state of the circuit breaker
+//
+// 返回熔断器的状态
+func (c *Connectors) BreakerState() BreakerState {
+	return c.breaker.State()
+}
+
+// 熔断器状态变化时输出日志并通知回调
+func (c *Connectors) breakerChanged(from, to BreakerState, err error) {
+	attrs := []slog.Attr{slog.String("from", from.String()), slog.String("to", to.String())}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.log(slog.LevelWarn, "gossdb circuit breaker state changed", attrs...)
+	for _, fn := range c.breakerCallbacks {
+		fn(from, to, err)
+	}
+}
+
+// 用新的连接 Ping 服务器
+func (c *Connectors) probe() error {
+	sc := ssdbclient.NewSSDBClient(c.config())
+	sc.Retry = nil
+	if err := sc.Start(); err != nil {
+		return err
+	}
+	defer sc.Close()
+	if !client.NewClient(sc, nil).Ping() {
+		return errors.New("gossdb ping failed")
+	}
+	return nil
+}
+
+// 连接相关的错误计入熔断器，ssdb 返回的错误状态不计入
+func (c *Connectors) recordBreaker(stat *ssdbclient.Stat) {
+	if stat.Err != nil && (stat.ErrKind == "panic" || ssdbclient.IsRetryable(stat.ErrKind, stat.Err)) {
+		c.breaker.record(stat.Err, time.Now())
+	} else {
+		c.breaker.record(nil, time.Now())
+	}
+}
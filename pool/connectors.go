@@ -1,9 +1,11 @@
 package pool
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math"
 	"runtime"
 	"sync"
@@ -13,6 +15,7 @@ import (
 	"github.com/seefan/gossdb/v2/client"
 	"github.com/seefan/gossdb/v2/conf"
 	"github.com/seefan/gossdb/v2/consts"
+	"github.com/seefan/gossdb/v2/slowlog"
 	"github.com/seefan/gossdb/v2/ssdbclient"
 )
 
@@ -22,25 +25,38 @@ import (
 type Connectors struct {
 	//当前连接池个数
 	cellPos int32
-	//状态 0：创建 1：正常 -1：关闭
-	status byte
+	//状态，见 consts.PoolStart、consts.PoolStopping、consts.PoolStop
+	status int32
+	//是否已经关闭，关闭后获取连接返回 ErrPoolClosed
+	closed int32
+	//关闭时通知后台协程退出
+	done     chan struct{}
+	doneLock sync.Mutex
+	//后台协程，Shutdown 时等待退出
+	background sync.WaitGroup
 	//最大等待数量
 	maxWait int32
 	//连接池最大个数
 	cellMax int32
 	//连接池最小个数
 	cellMin int32
-	//pool
-	cell []*Pool
+	//pool，扩容时整体替换
+	cell atomic.Pointer[[]*Pool]
+	//保护连接池块的创建和 cell 的扩容
+	resizeLock sync.Mutex
+	//连接配置的版本，修改连接配置后旧版本的连接将被重新打开
+	generation int32
 
 	//This function is called when automatic serialization is performed, and it can be modified to use a custom serialization method
 	//进行自动序列化时将调用这个函数，修改它可以使用自定义的序列化方式
 	EncodingFunc func(v interface{}) []byte
-	//config
-	cfg *conf.Config
-	//心跳检查
-	//等待池
-	poolWait chan *Client //连接池
+	//retry policy of the connections, created from the config, it can be modified before Start
+	//连接的重试策略，根据配置创建，可以在 Start 之前修改
+	RetryPolicy *ssdbclient.RetryPolicy
+	//config，运行中可以通过 Reconfigure 整体替换
+	cfg atomic.Pointer[conf.Config]
+	//等待队列
+	waiters waitQueue
 	//最后的动作时间
 	//
 	//watchTicker
@@ -70,6 +86,24 @@ type Connectors struct {
 	totalReturnFail int32
 	//运行总时长
 	totalParallelTime int64
+	//指标
+	metrics *poolMetrics
+	//观察者
+	observers []Observer
+	//中间件
+	middlewares []client.Middleware
+	//慢日志
+	slowLog *slowlog.Log
+	//熔断器
+	breaker *breaker
+	//熔断器状态变化的回调
+	breakerCallbacks []func(from, to BreakerState, err error)
+	//对冲读
+	hedger *hedger
+	//预热进度
+	warm warmUp
+	//泄漏检测
+	leaks *leakDetector
 }
 
 //NewConnectors initialize the connection pool using the configuration
@@ -79,13 +113,13 @@ type Connectors struct {
 //使用配置初始化连接池
 func NewConnectors(cfg *conf.Config) *Connectors {
 	this := new(Connectors)
-	this.cfg = cfg.Default()
+	this.cfg.Store(cfg.Default())
 	this.cellMax = int32(math.Floor(float64(cfg.MaxPoolSize) / float64(cfg.PoolSize)))
 	this.cellMin = int32(math.Floor(float64(cfg.MinPoolSize) / float64(cfg.PoolSize)))
 	this.maxWait = int32(cfg.MaxWaitSize)
-	this.poolWait = make(chan *Client, cfg.MaxWaitSize)
 	this.watchTicker = time.NewTicker(time.Second)
-	this.cell = make([]*Pool, this.cellMax)
+	cells := make([]*Pool, this.cellMax)
+	this.cell.Store(&cells)
 
 	this.EncodingFunc = func(v interface{}) []byte {
 		if bs, err := json.Marshal(v); err == nil {
@@ -95,17 +129,27 @@ func NewConnectors(cfg *conf.Config) *Connectors {
 	}
 	this.clientTemp = &sync.Pool{
 		New: func() interface{} {
-			return &Client{Client: client.Client{}}
+			//带有错误的连接，Close 时放回这里
+			return &Client{Client: client.Client{}, over: this}
 		},
 	}
 	this.timerTemp = &sync.Pool{
 		New: func() interface{} {
-			t := time.NewTimer(time.Duration(this.cfg.GetClientTimeout) * time.Second)
+			t := time.NewTimer(time.Duration(this.config().GetClientTimeout) * time.Second)
 			t.Stop()
 			return t
 		},
 	}
-	this.status = consts.PoolStop
+	this.RetryPolicy = ssdbclient.NewRetryPolicy(this.config())
+	this.breaker = newBreaker(this.config())
+	this.breaker.probe = this.probe
+	this.breaker.onChange = this.breakerChanged
+	this.breaker.background = &this.background
+	this.hedger = newHedger(this, this.config())
+	this.leaks = newLeakDetector(this.config())
+	this.metrics = newPoolMetrics(this)
+	this.slowLog = slowlog.New(cfg.SlowLogSize, time.Duration(cfg.SlowLogMillisecond)*time.Millisecond, cfg.Logger)
+	atomic.StoreInt32(&this.status, consts.PoolStop)
 	return this
 }
 
@@ -114,13 +158,22 @@ func NewConnectors(cfg *conf.Config) *Connectors {
 // 标记的条件为如果活跃连接数不足，测试将连接池块长度缩减，然后检查该连接池块的连接有没有全部回收，如果全部回收就进行标记
 // 在下一个检查周期，将标记的块回收
 // 在检查周期过程中标记状态可能改变，如果块重用，将块内所有连接的状态检查一下，没有open的重新start一下
-func (c *Connectors) watchHealth() {
-
-	for v := range c.watchTicker.C {
+func (c *Connectors) watchHealth(done chan struct{}) {
+	defer c.background.Done()
+	for {
+		var v time.Time
+		select {
+		case <-done:
+			return
+		case v = <-c.watchTicker.C:
+		}
+		c.breaker.tick(v)
+		c.sweep(v)
+		c.checkLeaks(v)
 		// println(c.Info())
 		waitCount := atomic.LoadInt32(&c.waitCount)
 		size := atomic.LoadInt32(&c.cellPos)
-		if c.cellMin != c.cellMax && v.Unix()%int64(c.cfg.HealthSecond) == 0 {
+		if atomic.LoadInt32(&c.cellMin) != atomic.LoadInt32(&c.cellMax) && v.Unix()%int64(c.config().HealthSecond) == 0 {
 			totalCreated := atomic.LoadInt32(&c.totalCreated)
 			atomic.StoreInt32(&c.totalCreated, 0)
 			atomic.StoreInt64(&c.totalCreateTime, 0)
@@ -130,16 +183,21 @@ func (c *Connectors) watchHealth() {
 			atomic.StoreInt32(&c.totalReturnFail, 0)
 			atomic.StoreInt64(&c.totalParallelTime, 0)
 			if waitCount == 0 {
-				if totalCreated < (size-1)*int32(c.cfg.PoolSize) && size-1 >= c.cellMin {
+				if totalCreated < (size-1)*int32(c.config().PoolSize) && size-1 >= atomic.LoadInt32(&c.cellMin) {
 					size = atomic.AddInt32(&c.cellPos, -1)
+					c.log(slog.LevelInfo, "gossdb pool shrunk", slog.Int("cells", int(size)), slog.Int("created", int(totalCreated)))
 				}
 				c.watchPool(size)
 			}
 		}
 		//todo 更保守的创建连接池的方案，避免连接池占用过多的连接
-		if waitCount > 0 && size < c.cellMax {
+		if waitCount > 0 && size < atomic.LoadInt32(&c.cellMax) {
 			if err := c.appendPool(); err != nil {
+				c.log(slog.LevelError, "gossdb pool grow failed", slog.Int("cells", int(size)), slog.Int("waiting", int(waitCount)), slog.String("error", err.Error()))
 				time.Sleep(time.Millisecond * 10)
+			} else {
+				//新的连接池块交给等待者
+				c.dispatch()
 			}
 		}
 	}
@@ -147,29 +205,53 @@ func (c *Connectors) watchHealth() {
 
 //检查一下可关闭的连接池块，如果没有活动连接，可以关闭
 func (c *Connectors) watchPool(size int32) {
-	for i := size; i < c.cellMax; i++ {
-		if c.cell[i] != nil {
-			c.cell[i].CheckClose()
+	//超过最大块数的块也要检查，Reconfigure 可能减小了最大块数
+	cells := c.cells()
+	for i := size; i < int32(len(cells)); i++ {
+		if p := cells[i]; p != nil {
+			before := atomic.LoadInt32(&p.status)
+			p.CheckClose()
+			if before != consts.PoolStop && atomic.LoadInt32(&p.status) == consts.PoolStop {
+				c.log(slog.LevelDebug, "gossdb pool cell stopped", slog.Int("cell", int(i)))
+			}
 		}
 	}
 }
 
 //初始化连接池
 func (c *Connectors) appendPool() (err error) {
+	c.resizeLock.Lock()
+	defer c.resizeLock.Unlock()
 	pos := atomic.LoadInt32(&c.cellPos)
-	if pos < c.cellMax {
-		p := c.cell[pos]
+	if pos < atomic.LoadInt32(&c.cellMax) {
+		cells := c.cells()
+		p := cells[pos]
+		if p != nil && p.size != c.config().PoolSize && atomic.LoadInt32(&p.status) == consts.PoolStop {
+			//已停止的块按新的大小重建
+			p.Close()
+			p = nil
+		}
 		if p == nil {
 			p = c.getPool()
-			c.cell[pos] = p
+			p.index = pos
+			//其它协程无锁读取块列表，修改副本后整体替换
+			grown := make([]*Pool, len(cells))
+			copy(grown, cells)
+			grown[pos] = p
+			c.cell.Store(&grown)
 		}
-		if p.status != consts.PoolStart {
-			if err = p.Start(); err != nil {
+		if atomic.LoadInt32(&p.status) != consts.PoolStart {
+			if c.config().LazyStart {
+				err = p.StartLazy()
+			} else {
+				err = p.Start()
+			}
+			if err != nil {
 				return err
 			}
 		}
-		p.index = pos
 		atomic.AddInt32(&c.cellPos, 1)
+		c.log(slog.LevelInfo, "gossdb pool cell appended", slog.Int("cell", int(pos)), slog.Int("cells", int(pos+1)), slog.Int("size", c.config().PoolSize))
 	}
 	//println("append pool", pos+1)
 	return nil
@@ -177,26 +259,62 @@ func (c *Connectors) appendPool() (err error) {
 
 //获取一个连接池，关键点是设置关闭函数，用于处理自动回收
 func (c *Connectors) getPool() *Pool {
-	p := newPool(c.cfg.PoolSize)
+	p := newPool(c.config().PoolSize, c.config().LockFreeQueue)
 	p.New = func() (*Client, error) {
-		sc := ssdbclient.NewSSDBClient(c.cfg)
-		err := sc.Start()
-		if err != nil {
+		return c.newClient(p, !c.config().LazyStart)
+	}
+	return p
+}
+
+//创建连接池块中的一个连接，open 为 false 时不打开连接
+func (c *Connectors) newClient(p *Pool, open bool) (*Client, error) {
+	sc := ssdbclient.NewSSDBClient(c.config())
+	sc.EncodingFunc = c.EncodingFunc
+	sc.Retry = c.RetryPolicy
+	sc.Observer = c.observe
+	cc := &Client{
+		over:       c,
+		pool:       p,
+		generation: atomic.LoadInt32(&c.generation),
+	}
+	//client.NewClient 复制 SSDBClient，复制后再打开连接，连接的状态只保存在复制后的连接中
+	cc.Client = *client.NewClient(sc, func() {
+		if cc.AutoClose {
+			cc.close()
+		}
+	})
+	if open {
+		if err := cc.SSDBClient.Start(); err != nil {
 			return nil, err
 		}
-		sc.EncodingFunc = c.EncodingFunc
-		cc := &Client{
-			over: c,
-			pool: p,
+	}
+	cc.Client.SetReopen(func() error {
+		if !cc.hedgeClosed {
+			return errClosedClient
 		}
-		cc.Client = *client.NewClient(sc, func() {
-			if cc.AutoClose {
-				cc.close()
-			}
-		})
-		return cc, nil
+		//对冲读关闭了连接，在下一个命令前重新打开
+		cc.hedgeClosed = false
+		return c.reconnect(cc, "hedge")
+	})
+	if c.leaks.enabled() {
+		//最外层检查连接是否已被强制回收
+		cc.Client.Use(c.leakMiddleware(cc))
 	}
-	return p
+	cc.Client.Use(c.middlewares...)
+	if c.hedger.percentile > 0 {
+		//对冲在最内层，外层中间件只看到一次调用
+		cc.Client.Use(c.hedger.middleware(cc))
+	}
+	return cc, nil
+}
+
+//Use add middlewares to all connections of the pool, the first one is the outermost. It must be called before Start.
+//
+//  @param mw middlewares
+//
+//为连接池的所有连接添加中间件，先添加的在最外层，必须在 Start 之前调用
+func (c *Connectors) Use(mw ...client.Middleware) {
+	c.middlewares = append(c.middlewares, mw...)
 }
 
 //Start start connectors
@@ -206,37 +324,65 @@ func (c *Connectors) getPool() *Pool {
 //启动连接池
 func (c *Connectors) Start() (err error) {
 	c.cellPos = 0
-	c.status = consts.PoolStart
-	for i := c.cellPos; i < c.cellMin && err == nil; i++ {
+	atomic.StoreInt32(&c.closed, 0)
+	atomic.StoreInt32(&c.status, consts.PoolStart)
+	done := make(chan struct{})
+	c.doneLock.Lock()
+	c.done = done
+	c.doneLock.Unlock()
+	c.watchTicker.Reset(time.Second)
+	c.waiters.open()
+	c.startWarmUp()
+	for i := c.cellPos; i < atomic.LoadInt32(&c.cellMin) && err == nil; i++ {
 		err = c.appendPool()
 	}
-	go c.watchHealth()
+	if c.config().LazyStart {
+		c.background.Add(1)
+		go func() {
+			defer c.background.Done()
+			c.warmUp()
+		}()
+	} else {
+		c.warmUpDone(err)
+	}
+	c.background.Add(1)
+	go c.watchHealth(done)
 	return
 }
 
 //回收Client
 func (c *Connectors) closeClient(client *Client) {
-	if c.status == consts.PoolStop {
+	if c.leaks.enabled() && !c.leaks.release(client) {
+		//已被泄漏检测强制回收
+		return
+	}
+	if atomic.LoadInt32(&c.status) == consts.PoolStop {
+		if client.used {
+			client.used = false
+			atomic.AddInt32(&c.available, -1)
+		}
 		if client.SSDBClient.IsOpen() {
 			_ = client.SSDBClient.Close()
 		}
 	} else {
 		client.used = false
+		client.SetContext(nil)
 		ts := time.Now().UnixNano() - client.OpenTime
 		atomic.AddInt64(&c.totalParallelTime, ts)
-		pc := atomic.AddInt32(&c.available, -1)
+		atomic.AddInt32(&c.available, -1)
+		client.idleSince = time.Now()
 		if client.SSDBClient.IsOpen() {
-			waitCount := atomic.LoadInt32(&c.waitCount)
-			if waitCount > 0 && pc%2 == 0 {
-				c.poolWait <- client
-			} else {
-				client.pool.Set(client)
-			}
+			//超过寿命或使用次数的连接关闭后放回，下次取出时重新连接
+			c.recycle(client, client.idleSince, false)
+		} else if client.hedgeClosed {
+			//对冲读主动关闭的连接不是失败，取出时重新打开
 		} else {
-			client.pool.Set(client)
 			atomic.StoreInt32(&client.pool.status, consts.PoolCheck)
 			atomic.AddInt32(&c.totalReturnFail, 1)
+			c.metrics.returnFails.Inc()
 		}
+		//有等待者时按优先级和先后顺序交给等待者
+		c.put(client)
 	}
 }
 
@@ -246,10 +392,20 @@ func (c *Connectors) closeClient(client *Client) {
 //
 //获取一个无错误的连接，如果有错误，将在调用连接的函数时返回
 func (c *Connectors) GetClient() *Client {
-	cc, err := c.NewClient()
+	return c.GetClientContext(context.Background())
+}
+
+//GetClientContext gets an error-free connection with the context, see GetClient and NewClientContext
+//
+// @param ctx context
+// @return *Client
+//
+//使用上下文获取一个无错误的连接
+func (c *Connectors) GetClientContext(ctx context.Context) *Client {
+	cc, err := c.NewClientContext(ctx)
 	//println("client get ", c.Info())
 	if err == nil {
-		if c.cfg.AutoClose {
+		if c.config().AutoClose {
 			cc.AutoClose = true
 		}
 		return cc
@@ -260,38 +416,85 @@ func (c *Connectors) GetClient() *Client {
 }
 func (c *Connectors) createClient() (cli *Client, err error) {
 	//首先按位置，直接取连接，给n次机会
-	size := atomic.LoadInt32(&c.cellPos)
-	pi := atomic.LoadInt32(&c.round)
 	for i := 0; i < 2; i++ {
-		pi += int32(i)
-		if pi >= size {
-			pi = 0
+		cli = c.takeClient(i)
+		if cli == nil {
+			runtime.Gosched()
+			continue
 		}
-		p := c.cell[pi]
-		if p.status != consts.PoolStop {
-			cli = p.Get()
-			if cli != nil {
-				cli.Error = nil
-				if p.health == consts.PoolCheck {
-					if !cli.Ping() {
-						err = cli.SSDBClient.Start()
-					}
-					p.CheckHeath()
-				} else if !cli.SSDBClient.IsOpen() {
-					err = cli.SSDBClient.Start()
-				}
-				if err == nil {
-					cli.used = true
-					if pi != cli.pool.index {
-						atomic.StoreInt32(&c.round, cli.pool.index)
-					}
+		if err = c.prepareClient(cli); err == nil {
+			return cli, nil
+		}
+		c.put(cli) //如果没有成功返回，就放回到连接池内，有等待者时交给等待者
+	}
+	return nil, err
+}
 
-					return cli, nil
-				}
-				p.Set(cli) //如果没有成功返回，就放回到连接池内
-			}
+//从上次取到连接的连接池块开始，取第 i 个块中的空闲连接，不检查连接，不执行网络操作
+func (c *Connectors) takeClient(i int) *Client {
+	size := atomic.LoadInt32(&c.cellPos)
+	if size <= 0 {
+		return nil
+	}
+	pi := (atomic.LoadInt32(&c.round) + int32(i)) % size
+	cells := c.cells()
+	if int(pi) >= len(cells) || cells[pi] == nil {
+		return nil
+	}
+	p := cells[pi]
+	if atomic.LoadInt32(&p.status) == consts.PoolStop {
+		return nil
+	}
+	cli := p.Get()
+	if cli != nil && pi != cli.pool.index {
+		atomic.StoreInt32(&c.round, cli.pool.index)
+	}
+	return cli
+}
+
+//检查取出的连接，需要时重新连接，成功后标记为使用中
+func (c *Connectors) prepareClient(cli *Client) (err error) {
+	cli.Error = nil
+	cli.hedgeClosed = false
+	//取出的连接不带有上一个使用者的异步接口
+	_ = cli.Client.DetachAsync()
+	if cli.SSDBClient.IsOpen() {
+		c.recycle(cli, time.Now(), true)
+	}
+	p := cli.pool
+	if p.health == consts.PoolCheck {
+		if !cli.Ping() {
+			err = c.reconnect(cli, "ping failed")
 		}
-		runtime.Gosched()
+		p.CheckHeath()
+	} else if !cli.SSDBClient.IsOpen() {
+		err = c.reconnect(cli, "closed")
+	}
+	if err == nil {
+		cli.used = true
+		cli.uses++
+	}
+	return
+}
+
+//重新打开连接
+func (c *Connectors) reconnect(cli *Client, reason string) (err error) {
+	attrs := []slog.Attr{slog.Uint64("conn_id", cli.ID()), slog.Int("cell", int(cli.pool.index)), slog.String("reason", reason)}
+	cli.uses = 0
+	if gen := atomic.LoadInt32(&c.generation); cli.generation != gen {
+		//Reconfigure 修改了连接配置
+		cli.SSDBClient.Configure(c.config())
+		cli.generation = gen
+	}
+	//延迟启动时第一次打开连接
+	first := cli.StartTime().IsZero()
+	if err = cli.SSDBClient.Start(); err != nil {
+		c.breaker.record(err, time.Now())
+		c.log(slog.LevelError, "gossdb reconnect failed", append(attrs, slog.String("error", err.Error()))...)
+	} else if first {
+		c.log(slog.LevelDebug, "gossdb connection opened", attrs...)
+	} else {
+		c.log(slog.LevelWarn, "gossdb reconnected", attrs...)
 	}
 	return
 }
@@ -303,60 +506,133 @@ func (c *Connectors) createClient() (cli *Client, err error) {
 //
 //在连接池取一个新连接，如果出错将返回一个错误
 func (c *Connectors) NewClient() (cli *Client, err error) {
-	if c.status != consts.PoolStart {
+	return c.NewClientContext(context.Background())
+}
+
+//NewClientContext take a new connection in the connection pool with the context.
+//The waiting can be canceled by the context, and the commands of the connection are traced as children of the span in the context.
+//
+//  @param ctx context
+//  @return client new client
+//  @return error possible error, operation successfully returned nil
+//
+//使用上下文在连接池取一个新连接，等待可以被上下文取消，连接执行的命令将作为上下文中 span 的子级被追踪
+func (c *Connectors) NewClientContext(ctx context.Context) (cli *Client, err error) {
+	if atomic.LoadInt32(&c.status) != consts.PoolStart {
+		if atomic.LoadInt32(&c.closed) == 1 {
+			return nil, ErrPoolClosed
+		}
 		return nil, errors.New("connectors not start")
 	}
+	waited := false
+	if len(c.observers) > 0 {
+		start := time.Now()
+		defer func() {
+			stat := &AcquireStat{Ctx: ctx, Start: start, Duration: time.Since(start), Waited: waited, Err: err}
+			for _, o := range c.observers {
+				o.ObserveAcquire(stat)
+			}
+		}()
+	}
+	//熔断器打开时直接失败
+	if err = c.breaker.allow(); err != nil {
+		c.metrics.errors.With("breaker_open").Inc()
+		return nil, err
+	}
 
 	atomic.AddInt32(&c.totalCreated, 1)
 	startTime := time.Now().UnixNano()
-	cli, err = c.createClient()
-	if cli != nil && err == nil {
-		atomic.AddInt32(&c.available, 1)
-		ts := time.Now().UnixNano() - startTime
-		atomic.AddInt64(&c.totalCreateTime, ts)
-		cli.OpenTime = startTime - ts
-		return
+	//有等待者时排在等待者后面，保证先到先得
+	if atomic.LoadInt32(&c.waitCount) == 0 {
+		if cli, err = c.createClient(); cli != nil && err == nil {
+			c.acquired(ctx, cli, startTime, false)
+			return
+		}
 	}
 
 	//enter slow pool
 	waitCount := atomic.LoadInt32(&c.waitCount)
-	if waitCount >= c.maxWait {
+	if waitCount >= atomic.LoadInt32(&c.maxWait) {
+		c.metrics.errors.With("pool_busy").Inc()
 		return nil, fmt.Errorf("pool is busy,Wait for connection creation has reached %d", waitCount)
 	}
 	waitCount = atomic.AddInt32(&c.waitCount, 1)
+	defer atomic.AddInt32(&c.waitCount, -1)
 	timeout := c.timerTemp.Get().(*time.Timer)
-	timeout.Reset(time.Duration(c.cfg.GetClientTimeout) * time.Second)
-	select {
-	case <-timeout.C:
-		atomic.AddInt32(&c.totalCreateTimeout, 1)
-		err = fmt.Errorf("pool is busy,can not get new client in %d seconds,wait count is %d", c.cfg.GetClientTimeout, waitCount)
-	case cli = <-c.poolWait:
-		if cli == nil {
-			err = errors.New("pool is Closed, can not get new client")
-		} else {
-			cli.used = true
-			err = nil
-			cli.OpenTime = time.Now().UnixNano()
-			atomic.AddInt32(&c.available, 1)
-			atomic.AddInt32(&c.totalCreateWait, 1)
-			ts := cli.OpenTime - startTime
-			atomic.AddInt64(&c.totalCreateTime, ts)
-			atomic.AddInt64(&c.totalCreateWaitTime, ts) //等待时长
+	timeout.Reset(time.Duration(c.config().GetClientTimeout) * time.Second)
+	defer func() {
+		timeout.Stop()
+		c.timerTemp.Put(timeout)
+	}()
+	//按优先级先进先出排队，归还的连接直接交给排在最前的等待者。连接在锁外检查，检查失败时回到队首继续等待
+	for retry := false; ; retry = true {
+		//之前取连接或检查失败的错误不保留，否则收到连接时会被当作超时
+		err = nil
+		w, raw := c.enqueue(ctx, retry)
+		if raw == nil {
+			if !waited {
+				waited = true
+				c.metrics.waits.Inc()
+			}
+			select {
+			case <-timeout.C:
+				atomic.AddInt32(&c.totalCreateTimeout, 1)
+				c.metrics.timeouts.Inc()
+				c.metrics.errors.With("acquire_timeout").Inc()
+				err = fmt.Errorf("pool is busy,can not get new client in %d seconds,wait count is %d", c.config().GetClientTimeout, waitCount)
+			case <-ctx.Done():
+				err = ctx.Err()
+			case raw = <-w.ch:
+				if raw == nil {
+					return nil, ErrPoolClosed
+				}
+			}
+			if err != nil {
+				if !c.waiters.remove(w) {
+					//超时的同时已经分到了连接，归还给其它等待者
+					if raw = <-w.ch; raw != nil {
+						c.put(raw)
+					}
+				}
+				return nil, err
+			}
+		}
+		if err = c.prepareClient(raw); err == nil {
+			c.acquired(ctx, raw, startTime, waited)
+			return raw, nil
 		}
+		//连接不可用，放回连接池并唤醒排队的等待者，自己等待下一个归还的连接
+		c.put(raw)
+	}
+}
+
+//取得连接后的统计
+func (c *Connectors) acquired(ctx context.Context, cli *Client, startTime int64, waited bool) {
+	cli.OpenTime = time.Now().UnixNano()
+	ts := cli.OpenTime - startTime
+	atomic.AddInt32(&c.available, 1)
+	atomic.AddInt64(&c.totalCreateTime, ts)
+	if waited {
+		atomic.AddInt32(&c.totalCreateWait, 1)
+		atomic.AddInt64(&c.totalCreateWaitTime, ts) //等待时长
+	}
+	cli.SetContext(ctx)
+	c.metrics.acquire(ts)
+	if c.leaks.enabled() {
+		c.leaks.acquire(cli)
 	}
-	atomic.AddInt32(&c.waitCount, -1)
-	timeout.Stop()
-	c.timerTemp.Put(timeout)
-	return
 }
 
-//Close close connectors
+//Close close connectors immediately, including the connections in use, see Shutdown for a graceful shutdown
 //
-//关闭连接池
+//立即关闭连接池，包括使用中的连接，优雅关闭请使用 Shutdown
 func (c *Connectors) Close() {
-	c.status = consts.PoolStop
+	atomic.StoreInt32(&c.closed, 1)
+	atomic.StoreInt32(&c.status, consts.PoolStop)
 	c.watchTicker.Stop()
-	for _, cc := range c.cell {
+	c.stopBackground()
+	c.waiters.closeAll()
+	for _, cc := range c.cells() {
 		if cc != nil {
 			cc.Close()
 		}
@@ -384,7 +660,7 @@ func (c *Connectors) Info() string {
 	}
 	inf := map[string]interface{}{
 		"created":            totalCreated,
-		"seconds":            c.cfg.HealthSecond,
+		"seconds":            c.config().HealthSecond,
 		"parallel":           atomic.LoadInt32(&c.available),
 		"waitCount":          atomic.LoadInt32(&c.waitCount),
 		"totalReturnFail":    atomic.LoadInt32(&c.totalReturnFail), //当前返回失败的计数
@@ -1,10 +1,23 @@
 package pool
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/seefan/gossdb/v2/client"
 	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/consts"
 )
 
 func BenchmarkConnectors_NewClient10(b *testing.B) {
@@ -372,3 +385,1282 @@ func BenchmarkConnectors_Get1k(b *testing.B) {
 	})
 	pool.Close()
 }
+
+func TestMetrics(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    10,
+		MinPoolSize: 10,
+		MaxPoolSize: 10,
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = c.Get("a")
+	_, _ = c.Do("unknown_command")
+	c.Close()
+	var buf bytes.Buffer
+	if _, err = pool.Metrics().WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"gossdb_pool_acquired_total 1\n",
+		"gossdb_command_seconds_count{command=\"get\"} 1\n",
+		"gossdb_errors_total{kind=\"server\"} 1\n",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("metrics not contains %q", s)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    5,
+		MinPoolSize: 10,
+		MaxPoolSize: 20,
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := pool.Stats()
+	if s.CellPos != 2 || s.CellMax != 4 || s.Total != 10 || s.InUse != 1 || s.Idle != 9 || len(s.Cells) != 2 || s.Acquired != 1 {
+		t.Errorf("stats error %+v", s)
+	}
+	c.Close()
+	if s = pool.Stats(); s.InUse != 0 || s.Idle != 10 || s.Cells[0].Open != 5 {
+		t.Errorf("stats error %+v", s)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    10,
+		MinPoolSize: 10,
+		MaxPoolSize: 10,
+	})
+	var cmds []string
+	pool.Use(func(next client.Handler) client.Handler {
+		return func(ctx context.Context, cmd string, args ...interface{}) ([]string, error) {
+			cmds = append(cmds, cmd)
+			return next(ctx, cmd, args...)
+		}
+	}, func(next client.Handler) client.Handler {
+		return func(ctx context.Context, cmd string, args ...interface{}) ([]string, error) {
+			if cmd == "del" {
+				return nil, errors.New("del is blocked")
+			}
+			return next(ctx, cmd, args...)
+		}
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err = c.Set("a", "1"); err != nil {
+		t.Error(err)
+	}
+	if err = c.Del("a"); err == nil {
+		t.Error("del is not blocked")
+	}
+	if v, err := c.Get("a"); err != nil || v != "1" {
+		t.Error(v, err)
+	}
+	if strings.Join(cmds, ",") != "set,del,get" {
+		t.Errorf("commands are %v", cmds)
+	}
+}
+
+func TestSlowLog(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:               "127.0.0.1",
+		Port:               8888,
+		PoolSize:           10,
+		MinPoolSize:        10,
+		MaxPoolSize:        10,
+		SlowLogMillisecond: 20,
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipWithoutSleep(t, c)
+	_, _ = c.Get("a")
+	_, _ = c.Do("sleep", 50)
+	id := c.ID()
+	c.Close()
+	es := pool.SlowLog().Entries()
+	if len(es) != 1 || es[0].Command != "sleep" || es[0].Args[0] != "50" || es[0].Duration < 50*time.Millisecond {
+		t.Fatal(es)
+	}
+	if es[0].ConnID != id {
+		t.Fatal("conn id", es[0].ConnID, id)
+	}
+}
+
+// 慢命令的测试使用测试服务的 sleep 命令，真实的 ssdb 没有这个命令，跳过测试
+func skipWithoutSleep(t *testing.T, c *Client) {
+	t.Helper()
+	if resp, err := c.Do("sleep", 0); err != nil || len(resp) == 0 || resp[0] != "ok" {
+		t.Skip("the server does not support the sleep command of the test server")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    5,
+		MinPoolSize: 10,
+		MaxPoolSize: 10,
+		Logger:      slog.New(slog.NewTextHandler(&buf, nil)),
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	pool.Close()
+	for _, s := range []string{"gossdb pool cell appended", "cell=0", "cell=1", "cells=2"} {
+		if !strings.Contains(out, s) {
+			t.Fatal(s, out)
+		}
+	}
+}
+
+// 逐个读取请求并返回 size 字节的值的服务，写响应时不读取后面的请求
+func serveLarge(t *testing.T, size int) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := []byte("2\nok\n" + strconv.Itoa(size) + "\n" + strings.Repeat("v", size) + "\n\n")
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					for {
+						line, err := r.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if line = strings.TrimRight(line, "\r\n"); line == "" {
+							break
+						}
+						n, _ := strconv.Atoi(line)
+						if _, err = r.Discard(n + 1); err != nil {
+							return
+						}
+					}
+					if _, err := conn.Write(resp); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+// 读取请求后关闭连接的服务，统计收到的 incr 次数
+func serveDropIncr(t *testing.T, incr *int32) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					var req []string
+					for {
+						line, err := r.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if line = strings.TrimRight(line, "\r\n"); line == "" {
+							break
+						}
+						size, _ := strconv.Atoi(line)
+						bs := make([]byte, size+1)
+						if _, err = io.ReadFull(r, bs); err != nil {
+							return
+						}
+						req = append(req, string(bs[:size]))
+					}
+					if len(req) > 0 && req[0] == "incr" {
+						//读取请求后断开连接，请求已经发出但没有响应
+						atomic.AddInt32(incr, 1)
+						return
+					}
+					if _, err := conn.Write([]byte("2\nok\n\n")); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func TestRetryWritten(t *testing.T) {
+	var incr int32
+	l := serveDropIncr(t, &incr)
+	defer l.Close()
+	addr := l.Addr().(*net.TCPAddr)
+	pool := NewConnectors(&conf.Config{
+		Host:             "127.0.0.1",
+		Port:             addr.Port,
+		PoolSize:         1,
+		MinPoolSize:      1,
+		MaxPoolSize:      1,
+		RetryEnabled:     true,
+		RetryMaxAttempts: 3,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	//已经发出的非幂等命令不重试
+	if _, err = c.Incr("retry_written", 1); err == nil {
+		t.Fatal("incr should fail")
+	}
+	if n := atomic.LoadInt32(&incr); n != 1 {
+		t.Fatal("incr is received", n, "times")
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:            "127.0.0.1",
+		Port:            8888,
+		PoolSize:        5,
+		MinPoolSize:     5,
+		MaxPoolSize:     5,
+		BreakerFailures: 2,
+	})
+	changes := make(chan BreakerState, 3)
+	pool.OnBreakerChange(func(from, to BreakerState, err error) {
+		changes <- to
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	failure := errors.New("connection reset")
+	pool.breaker.record(failure, time.Now())
+	if pool.BreakerState() != BreakerClosed {
+		t.Fatal("opened after one failure")
+	}
+	pool.breaker.record(failure, time.Now())
+	if s := <-changes; s != BreakerOpen {
+		t.Fatal(s)
+	}
+	_, err = pool.NewClient()
+	var open *CircuitOpenError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &open) || !errors.Is(err, failure) {
+		t.Fatal(err)
+	}
+	if s := pool.Stats(); s.Breaker != BreakerOpen || s.BreakerTrips != 1 || s.BreakerRejected != 1 {
+		t.Fatal(s.Breaker, s.BreakerTrips, s.BreakerRejected)
+	}
+	//打开时间到期后半开，Ping 成功后关闭
+	pool.breaker.tick(time.Now().Add(time.Minute))
+	if s := <-changes; s != BreakerHalfOpen {
+		t.Fatal(s)
+	}
+	if s := <-changes; s != BreakerClosed {
+		t.Fatal(s)
+	}
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+func TestBreakerShutdown(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:            "127.0.0.1",
+		Port:            8888,
+		PoolSize:        1,
+		MinPoolSize:     1,
+		MaxPoolSize:     1,
+		BreakerFailures: 1,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	var probed int32
+	pool.breaker.probe = func() error {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&probed, 1)
+		return nil
+	}
+	pool.breaker.record(errors.New("connection reset"), time.Now())
+	pool.breaker.tick(time.Now().Add(time.Minute))
+	//关闭连接池时等待半开状态的探测结束
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&probed) != 1 {
+		t.Fatal("shut down before the probe finished")
+	}
+}
+
+func TestBreakerErrorRate(t *testing.T) {
+	b := newBreaker((&conf.Config{BreakerErrorRate: 50, BreakerMinRequests: 4}).Default())
+	now := time.Now()
+	failure := errors.New("timeout")
+	for _, err := range []error{nil, failure, nil, failure} {
+		if b.State() != BreakerClosed {
+			t.Fatal("opened before min requests")
+		}
+		b.record(err, now)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatal(b.State())
+	}
+	var probes int32
+	b.probe = func() error {
+		atomic.AddInt32(&probes, 1)
+		return failure
+	}
+	//获取连接的协程不开始探测，只由健康检查协程的 tick 开始
+	b.openedAt = now.Add(-b.openTime)
+	if b.allow() == nil || b.State() != BreakerOpen {
+		t.Fatal("allowed while open", b.State())
+	}
+	b.tick(now)
+	for b.State() == BreakerHalfOpen {
+		time.Sleep(time.Millisecond)
+	}
+	if b.State() != BreakerOpen || b.allow() == nil || atomic.LoadInt32(&probes) != 1 {
+		t.Fatal("probe failure should reopen", b.State())
+	}
+}
+
+func TestHedge(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:            "127.0.0.1",
+		Port:            8888,
+		PoolSize:        5,
+		MinPoolSize:     5,
+		MaxPoolSize:     5,
+		HedgePercentile: 50,
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	skipWithoutSleep(t, c)
+	if err = c.Set("hedge", "v"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < latencyMinSamples; i++ {
+		if _, err = c.Get("hedge"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pool.hedger.delay("get") <= 0 {
+		t.Fatal("no hedge delay")
+	}
+	//主请求很慢，对冲请求先返回
+	slow := pool.hedger.middleware(c)(func(ctx context.Context, cmd string, args ...interface{}) ([]string, error) {
+		return c.SSDBClient.Do("sleep", 500)
+	})
+	start := time.Now()
+	resp, err := slow(context.Background(), "get", "hedge")
+	if err != nil || len(resp) != 2 || resp[1] != "v" {
+		t.Fatal(resp, err)
+	}
+	if d := time.Since(start); d > 300*time.Millisecond {
+		t.Fatal("not hedged", d)
+	}
+	if s := pool.Stats(); s.Hedged != 1 || s.HedgeWins != 1 || s.InUse != 1 {
+		t.Fatal(s.Hedged, s.HedgeWins, s.InUse)
+	}
+	//返回结果前不重新连接，下一个命令前重新打开
+	if c.SSDBClient.IsOpen() {
+		t.Fatal("the primary is reopened before the result is returned")
+	}
+	if v, err := c.Get("hedge"); err != nil || v.String() != "v" {
+		t.Fatal(v, err)
+	}
+	//对冲关闭的连接归还时不作为失败
+	if _, err = slow(context.Background(), "get", "hedge"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if n := atomic.LoadInt32(&pool.totalReturnFail); n != 0 || atomic.LoadInt32(&c.pool.status) == consts.PoolCheck {
+		t.Fatal("the hedge close is counted as a failed return", n)
+	}
+}
+
+func TestRecycle(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:           "127.0.0.1",
+		Port:           8888,
+		PoolSize:       1,
+		MinPoolSize:    1,
+		MaxPoolSize:    1,
+		MaxUsesPerConn: 2,
+		MaxIdleTime:    1,
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	var started []time.Time
+	for i := 0; i < 3; i++ {
+		c, err := pool.NewClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !c.Ping() {
+			t.Fatal("ping failed")
+		}
+		started = append(started, c.StartTime())
+		c.Close()
+	}
+	if !started[0].Equal(started[1]) || started[1].Equal(started[2]) {
+		t.Fatal("not recycled after max uses", started)
+	}
+	if s := pool.Stats(); s.Recycled != 1 || s.ReturnFailures != 0 {
+		t.Fatal(s.Recycled, s.ReturnFailures)
+	}
+	pool.sweep(time.Now().Add(2 * time.Second))
+	if s := pool.Stats(); s.Recycled != 2 || s.Cells[0].Open != 0 {
+		t.Fatal("idle connection not recycled", s.Recycled, s.Cells[0].Open)
+	}
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if !c.Ping() {
+		t.Fatal("ping failed")
+	}
+}
+
+func TestLazyStart(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    5,
+		MinPoolSize: 10,
+		MaxPoolSize: 10,
+		LazyStart:   true,
+	})
+	var steps, done int32
+	pool.OnWarmUp(func(p WarmUpProgress) {
+		atomic.AddInt32(&steps, 1)
+		if p.Done && p.Opened == 10 {
+			atomic.AddInt32(&done, 1)
+		}
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Ping() {
+		t.Fatal("ping failed")
+	}
+	c.Close()
+	<-pool.Ready()
+	p := pool.WarmUpProgress()
+	//每个连接一次，完成时再一次
+	if !p.Done || p.Total != 10 || p.Opened != 10 || p.Failed != 0 || atomic.LoadInt32(&steps) != 11 || atomic.LoadInt32(&done) != 1 {
+		t.Fatal(p, steps, done)
+	}
+	if s := pool.Stats(); s.Cells[0].Open+s.Cells[1].Open != 10 {
+		t.Fatal(s.Cells)
+	}
+}
+
+func TestLazyStartUnreachable(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:             "127.0.0.1",
+		Port:             1,
+		PoolSize:         2,
+		MinPoolSize:      2,
+		MaxPoolSize:      2,
+		GetClientTimeout: 1,
+		LazyStart:        true,
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	<-pool.Ready()
+	if p := pool.WarmUpProgress(); p.Opened != 0 || p.Failed != 2 || p.Err == nil {
+		t.Fatal(p)
+	}
+	if _, err = pool.NewClient(); err == nil {
+		t.Fatal("connected to unreachable server")
+	}
+}
+
+func TestPriorityWait(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    1,
+		MinPoolSize: 1,
+		MaxPoolSize: 1,
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	held, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lock sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	acquire := func(name string, p Priority, queued int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := pool.NewClientContext(WithPriority(context.Background(), p))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			lock.Lock()
+			order = append(order, name)
+			lock.Unlock()
+			c.Close()
+		}()
+		for {
+			n := 0
+			for _, v := range pool.Stats().WaitingByPriority {
+				n += v
+			}
+			if n >= queued {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	acquire("low", PriorityLow, 1)
+	acquire("normal1", PriorityNormal, 2)
+	acquire("high", PriorityHigh, 3)
+	acquire("normal2", PriorityNormal, 4)
+	if s := pool.Stats().WaitingByPriority; s[PriorityHigh] != 1 || s[PriorityNormal] != 2 || s[PriorityLow] != 1 {
+		t.Fatal(s)
+	}
+	held.Close()
+	wg.Wait()
+	if strings.Join(order, ",") != "high,normal1,normal2,low" {
+		t.Fatal(order)
+	}
+	if s := pool.Stats(); s.InUse != 0 || s.Idle != 1 {
+		t.Fatal(s.InUse, s.Idle)
+	}
+}
+
+func TestWaitFIFO(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    1,
+		MinPoolSize: 1,
+		MaxPoolSize: 1,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	held, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan *Client, 1)
+	go func() {
+		c, err := pool.NewClient()
+		if err != nil {
+			t.Error(err)
+		}
+		got <- c
+	}()
+	for pool.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	//连接回到连接池但还没有交给等待者时，新的调用排在等待者后面，不能直接取走
+	atomic.AddInt32(&pool.available, -1)
+	held.used = false
+	held.pool.Set(held)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if c, err := pool.NewClientContext(ctx); err == nil {
+		c.Close()
+		t.Fatal("the new caller took the client ahead of the waiter")
+	}
+	pool.dispatch()
+	c := <-got
+	if c == nil {
+		t.Fatal("the waiter got no client")
+	}
+	c.Close()
+}
+
+func TestLeak(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:             "127.0.0.1",
+		Port:             8888,
+		PoolSize:         1,
+		MinPoolSize:      1,
+		MaxPoolSize:      1,
+		GetClientTimeout: 1,
+		LeakDetectSecond: 1,
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	pool.checkLeaks(time.Now())
+	leaks := pool.Leaks()
+	if len(leaks) != 1 || leaks[0].ConnID != c.ID() || !strings.Contains(leaks[0].Stack, "TestLeak") {
+		t.Fatal(leaks)
+	}
+	if s := pool.Stats(); s.Leaked != 1 || s.LeaksFound != 1 {
+		t.Fatal(s.Leaked, s.LeaksFound)
+	}
+	//强制回收后原连接不能再使用，连接池中换成新的连接
+	pool.leaks.reclaim = true
+	pool.checkLeaks(time.Now())
+	if _, err = c.Do("get", "a"); err != ErrLeakReclaimed {
+		t.Fatal(err)
+	}
+	c.Close()
+	if s := pool.Stats(); s.Leaked != 0 || s.LeaksReclaimed != 1 || s.InUse != 0 || s.Idle != 1 {
+		t.Fatal(s.Leaked, s.LeaksReclaimed, s.InUse, s.Idle)
+	}
+	c2, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c2 == c || !c2.Ping() {
+		t.Fatal("not replaced")
+	}
+	c2.Close()
+	if len(pool.Leaks()) != 0 {
+		t.Fatal(pool.Leaks())
+	}
+}
+
+func TestLeakReclaimInUse(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:             "127.0.0.1",
+		Port:             8888,
+		PoolSize:         1,
+		MinPoolSize:      1,
+		MaxPoolSize:      1,
+		LeakDetectSecond: 1,
+		LeakReclaim:      true,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//持有者一直在执行命令时强制回收
+	stopped := make(chan error)
+	go func() {
+		for {
+			if _, err := c.Do("get", "a"); err != nil {
+				c.Close()
+				stopped <- err
+				return
+			}
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	pool.checkLeaks(time.Now().Add(2 * time.Second))
+	if err = <-stopped; err != ErrLeakReclaimed {
+		t.Fatal(err)
+	}
+	if _, err = c.Do("get", "a"); err != ErrLeakReclaimed {
+		t.Fatal(err)
+	}
+	if s := pool.Stats(); s.LeaksReclaimed != 1 || s.InUse != 0 {
+		t.Fatal(s.LeaksReclaimed, s.InUse)
+	}
+}
+
+func TestWith(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    1,
+		MinPoolSize: 1,
+		MaxPoolSize: 1,
+	})
+	err := pool.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	err = pool.With(context.Background(), func(c *client.Client) error {
+		return c.Set("with", "v")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := pool.Do("get", "with"); err != nil || len(resp) != 2 || resp[1] != "v" {
+		t.Fatal(resp, err)
+	}
+	//panic 时归还并关闭连接
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic not propagated")
+			}
+		}()
+		_ = pool.With(context.Background(), func(c *client.Client) error {
+			panic("boom")
+		})
+	}()
+	if s := pool.Stats(); s.InUse != 0 || s.Idle != 1 || s.Cells[0].Open != 0 {
+		t.Fatal(s.InUse, s.Idle, s.Cells[0].Open)
+	}
+	//错误状态的连接归还前关闭
+	failure := errors.New("failed")
+	err = pool.With(context.Background(), func(c *client.Client) error {
+		c.Error = failure
+		return failure
+	})
+	if err != failure {
+		t.Fatal(err)
+	}
+	if resp, err := pool.Do("get", "with"); err != nil || resp[1] != "v" {
+		t.Fatal(resp, err)
+	}
+	if s := pool.Stats(); s.InUse != 0 || s.Idle != 1 {
+		t.Fatal(s.InUse, s.Idle)
+	}
+}
+
+func TestAsync(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    1,
+		MinPoolSize: 1,
+		MaxPoolSize: 1,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipWithoutSleep(t, c)
+	c.AutoClose = true
+	a := c.Async()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	gets := make(map[int]*client.Future[client.Value])
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			k := "async:" + strconv.Itoa(i)
+			set := a.Set(k, i)
+			mu.Lock()
+			gets[i] = a.Get(k)
+			mu.Unlock()
+			if _, err := set.Wait(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, f := range gets {
+		<-f.Done()
+		if v, err := f.Wait(context.Background()); err != nil || v.Int() != i {
+			t.Errorf("get %d: %q %v", i, v, err)
+		}
+	}
+	//Close 等待已发送的命令完成
+	slow := a.Do("sleep", 50)
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := slow.Wait(context.Background()); err != nil || resp[0] != "ok" {
+		t.Fatal(resp, err)
+	}
+	//AutoClose 的连接在 Close 后归还
+	if s := pool.Stats(); s.InUse != 0 {
+		t.Fatal(s.InUse)
+	}
+}
+
+func TestAsync_large(t *testing.T) {
+	const size = 1 << 20
+	l := serveLarge(t, size)
+	defer l.Close()
+	pool := NewConnectors(&conf.Config{
+		Host:             "127.0.0.1",
+		Port:             l.Addr().(*net.TCPAddr).Port,
+		PoolSize:         1,
+		MinPoolSize:      1,
+		MaxPoolSize:      1,
+		ReadWriteTimeout: 5,
+		ReadBufferSize:   128,
+		WriteBufferSize:  128,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	//大的请求和大的响应同时在流水线上，发送时不能阻塞接收
+	big := strings.Repeat("v", size)
+	a := c.Async()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				if resp, err := a.Do("set", "async:large", big).Wait(ctx); err != nil || len(resp) != 2 || len(resp[1]) != size {
+					t.Error(len(resp), err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAsync_errorClient(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	})
+	//连接池没有启动，返回带有错误的连接
+	c := pool.GetClient()
+	if c.Error == nil {
+		t.Fatal("no error")
+	}
+	if _, err := c.Async().Get("a").Wait(context.Background()); err != c.Error {
+		t.Fatal(err)
+	}
+	c.Close()
+	//没有自己的 socket 的连接不支持异步接口
+	h := client.NewHandlerClient(func(ctx context.Context, cmd string, args ...interface{}) ([]string, error) {
+		return []string{"ok"}, nil
+	})
+	if _, err := h.Async().Do("get", "a").Wait(context.Background()); err != client.ErrAsyncUnsupported {
+		t.Fatal(err)
+	}
+}
+
+func TestAsync_detach(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    1,
+		MinPoolSize: 1,
+		MaxPoolSize: 1,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	c, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//多个协程同时取异步接口，得到同一个值
+	as := make([]*client.Async, 10)
+	var wg sync.WaitGroup
+	for i := range as {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			as[i] = c.Async()
+		}(i)
+	}
+	wg.Wait()
+	for _, a := range as[1:] {
+		if a != as[0] {
+			t.Fatal("different asynchronous api")
+		}
+	}
+	fs := make([]*client.Future[struct{}], 20)
+	for i := range fs {
+		fs[i] = as[0].Set("async:detach", i)
+	}
+	//归还连接时等待已发送的命令完成，异步接口和连接分离
+	c.Close()
+	for _, f := range fs {
+		select {
+		case <-f.Done():
+		default:
+			t.Fatal("the pending command is not finished before the client is returned")
+		}
+		if _, err = f.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = as[0].Get("async:detach").Wait(context.Background()); err != client.ErrAsyncDetached {
+		t.Fatal(err)
+	}
+	if err = as[0].Close(); err != client.ErrAsyncDetached {
+		t.Fatal(err)
+	}
+	//连接再次取出后使用新的异步接口
+	c, err = pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.Async() == as[0] {
+		t.Fatal("the asynchronous api is reused")
+	}
+	if v, err := c.Async().Get("async:detach").Wait(context.Background()); err != nil || v.Int() != len(fs)-1 {
+		t.Fatal(v, err)
+	}
+	if s := pool.Stats(); s.InUse != 1 {
+		t.Fatal(s.InUse)
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    2,
+		MinPoolSize: 2,
+		MaxPoolSize: 4,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	//增长到新的最小值，最大值超过原来的块数
+	err := pool.Reconfigure(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    2,
+		MinPoolSize: 6,
+		MaxPoolSize: 10,
+		MaxWaitSize: 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := pool.Stats(); s.CellPos != 3 || s.CellMin != 3 || s.CellMax != 5 || s.MaxWait != 50 || s.Total != 6 {
+		t.Fatal(s.CellPos, s.CellMin, s.CellMax, s.MaxWait, s.Total)
+	}
+	//修改连接配置后连接取出时重新打开
+	err = pool.Reconfigure(&conf.Config{
+		Host:             "127.0.0.1",
+		Port:             1,
+		PoolSize:         2,
+		MinPoolSize:      2,
+		MaxPoolSize:      4,
+		GetClientTimeout: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := pool.Stats(); s.CellPos != 2 || s.CellMax != 2 {
+		t.Fatal(s.CellPos, s.CellMax)
+	}
+	if _, err = pool.Do("get", "a"); err == nil {
+		t.Fatal("connected to the old port")
+	}
+	err = pool.Reconfigure(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    2,
+		MinPoolSize: 2,
+		MaxPoolSize: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pool.Do("set", "reconfigure", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if s := pool.Stats(); s.Recycled == 0 {
+		t.Fatal("not recycled")
+	}
+}
+
+func TestReconfigure_shrink(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:          "127.0.0.1",
+		Port:          8888,
+		PoolSize:      2,
+		MinPoolSize:   6,
+		MaxPoolSize:   6,
+		RetryEnabled:  true,
+		SlowLogSize:   10,
+		LockFreeQueue: true,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	//从最后一个块中取出一个连接
+	atomic.StoreInt32(&pool.round, 2)
+	inUse, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inUse.pool.index != 2 {
+		t.Fatal(inUse.pool.index)
+	}
+	//增长时其它协程同时取连接
+	var wg sync.WaitGroup
+	for i := 0; i < 1; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := pool.Do("get", "a"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	err = pool.Reconfigure(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    2,
+		MinPoolSize: 10,
+		MaxPoolSize: 10,
+	})
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//不能修改的配置保持原值
+	if cfg := pool.config(); !cfg.RetryEnabled || cfg.SlowLogSize != 10 || !cfg.LockFreeQueue {
+		t.Fatalf("fixed settings changed %+v", cfg)
+	}
+	//最大值减小后超过最大值的块立即停止，空闲连接关闭，使用中的连接归还时关闭
+	err = pool.Reconfigure(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    2,
+		MinPoolSize: 4,
+		MaxPoolSize: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := pool.cells()
+	for i := 2; i < len(cells); i++ {
+		if s := cells[i].Stats(); s.Status != consts.PoolStop || s.Open != s.InUse {
+			t.Fatal(i, s.Status, s.Open, s.InUse)
+		}
+	}
+	if !inUse.IsOpen() {
+		t.Fatal("the client in use is closed")
+	}
+	inUse.Close()
+	if s := cells[2].Stats(); s.Open != 0 {
+		t.Fatal("the returned client is not closed", s.Open)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err = pool.Do("get", "a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:        "127.0.0.1",
+		Port:        8888,
+		PoolSize:    1,
+		MinPoolSize: 1,
+		MaxPoolSize: 1,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	held, err := pool.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitErr := make(chan error, 1)
+	go func() {
+		_, err := pool.NewClient()
+		waitErr <- err
+	}()
+	for pool.Stats().WaitingByPriority[PriorityNormal] == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- pool.Shutdown(context.Background())
+	}()
+	//等待者和新的获取立即失败
+	if err = <-waitErr; !errors.Is(err, ErrPoolClosed) {
+		t.Fatal(err)
+	}
+	if _, err = pool.NewClient(); !errors.Is(err, ErrPoolClosed) {
+		t.Fatal(err)
+	}
+	//使用中的连接仍可以执行命令，归还后关闭完成
+	if err = held.Set("shutdown", "v"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-shutdown:
+		t.Fatal("shut down with a client in use", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	held.Close()
+	if err = <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if held.IsOpen() {
+		t.Fatal("connection not closed")
+	}
+
+	//上下文结束时强制关闭
+	if err = pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if held, err = pool.NewClient(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err = pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	if held.IsOpen() {
+		t.Fatal("connection not closed")
+	}
+	held.Close()
+	if s := pool.Stats(); s.InUse != 0 {
+		t.Fatal(s.InUse)
+	}
+}
+
+func TestLockFreeQueue(t *testing.T) {
+	pool := NewConnectors(&conf.Config{
+		Host:          "127.0.0.1",
+		Port:          8888,
+		PoolSize:      4,
+		MinPoolSize:   4,
+		MaxPoolSize:   8,
+		LockFreeQueue: true,
+	})
+	if err := pool.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			k := "lockfree:" + strconv.Itoa(i)
+			for j := 0; j < 20; j++ {
+				if _, err := pool.Do("set", k, j); err != nil {
+					t.Error(err)
+					return
+				}
+				if resp, err := pool.Do("get", k); err != nil || resp[1] != strconv.Itoa(j) {
+					t.Error(resp, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if s := pool.Stats(); s.InUse != 0 || s.Idle != s.Total {
+		t.Fatal(s.InUse, s.Idle, s.Total)
+	}
+}
@@ -0,0 +1,205 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/seefan/gossdb/v2/client"
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/consts"
+	"github.com/seefan/gossdb/v2/metrics"
+	"github.com/seefan/gossdb/v2/ssdbclient"
+)
+
+const (
+	//每个命令保留的耗时样本数
+	latencySamples = 128
+	//开始对冲前至少需要的样本数
+	latencyMinSamples = 20
+)
+
+// 最近的命令耗时，用于计算对冲的延迟
+type latencyWindow struct {
+	lock    sync.Mutex
+	samples [latencySamples]time.Duration
+	//样本数
+	n int
+	//下一个写入的位置
+	pos int
+	//缓存的分位数
+	cached time.Duration
+	//缓存后新增的样本数
+	dirty int
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	w.lock.Lock()
+	w.samples[w.pos] = d
+	w.pos = (w.pos + 1) % latencySamples
+	if w.n < latencySamples {
+		w.n++
+	}
+	w.dirty++
+	w.lock.Unlock()
+}
+
+// 返回分位数，样本不足时返回 0，每新增 16 个样本重新计算一次
+func (w *latencyWindow) percentile(p int) time.Duration {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.n < latencyMinSamples {
+		return 0
+	}
+	if w.cached == 0 || w.dirty >= 16 {
+		s := make([]time.Duration, w.n)
+		copy(s, w.samples[:w.n])
+		sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+		w.cached = s[(w.n-1)*p/100]
+		w.dirty = 0
+	}
+	return w.cached
+}
+
+// 对冲读，只读命令在延迟内没有返回时，用另一个连接再发送一次，返回先到的结果
+type hedger struct {
+	c *Connectors
+	//分位数，0 不启用
+	percentile int
+	//最小延迟
+	minDelay time.Duration
+	//按命令统计的耗时
+	latency sync.Map
+	//发起对冲的次数
+	hedged *metrics.Counter
+	//对冲先返回的次数
+	wins *metrics.Counter
+}
+
+func newHedger(c *Connectors, cfg *conf.Config) *hedger {
+	return &hedger{
+		c:          c,
+		percentile: cfg.HedgePercentile,
+		minDelay:   time.Duration(cfg.HedgeMinDelayMillisecond) * time.Millisecond,
+		hedged:     &metrics.Counter{},
+		wins:       &metrics.Counter{},
+	}
+}
+
+// 记录成功的只读命令的耗时
+func (h *hedger) observe(stat *ssdbclient.Stat) {
+	if h.percentile <= 0 || stat.Err != nil || stat.Attempts > 1 {
+		return
+	}
+	cmd := stat.Command()
+	if !ssdbclient.IsReadOnly(cmd) {
+		return
+	}
+	w, ok := h.latency.Load(cmd)
+	if !ok {
+		w, _ = h.latency.LoadOrStore(cmd, &latencyWindow{})
+	}
+	w.(*latencyWindow).add(stat.Duration)
+}
+
+// 对冲的延迟，样本不足时返回 0 不对冲
+func (h *hedger) delay(cmd string) time.Duration {
+	w, ok := h.latency.Load(cmd)
+	if !ok {
+		return 0
+	}
+	d := w.(*latencyWindow).percentile(h.percentile)
+	if d > 0 && d < h.minDelay {
+		d = h.minDelay
+	}
+	return d
+}
+
+// 使用已关闭的连接，与 client.Client.Do 的错误一致
+var errClosedClient = errors.New("use the closed connection")
+
+type hedgeResult struct {
+	resp  []string
+	err   error
+	hedge bool
+}
+
+// 对冲中间件，作为最内层中间件安装在每个连接上
+func (h *hedger) middleware(cc *Client) client.Middleware {
+	return func(next client.Handler) client.Handler {
+		return func(ctx context.Context, cmd string, args ...interface{}) ([]string, error) {
+			if !ssdbclient.IsReadOnly(cmd) {
+				return next(ctx, cmd, args...)
+			}
+			d := h.delay(cmd)
+			if d <= 0 {
+				return next(ctx, cmd, args...)
+			}
+			done := make(chan hedgeResult, 2)
+			go func() {
+				resp, err := next(ctx, cmd, args...)
+				done <- hedgeResult{resp: resp, err: err}
+			}()
+			timer := time.NewTimer(d)
+			select {
+			case r := <-done:
+				timer.Stop()
+				return r.resp, r.err
+			case <-timer.C:
+			}
+			//主请求超过延迟，从连接池直接取一个空闲连接再发送一次，没有空闲连接时继续等待主请求
+			hc := h.c.tryClient(ctx)
+			if hc == nil {
+				r := <-done
+				return r.resp, r.err
+			}
+			h.hedged.Inc()
+			go func() {
+				resp, err := hc.SSDBClient.Do(append([]interface{}{cmd}, args...)...)
+				hc.close()
+				done <- hedgeResult{resp: resp, err: err, hedge: true}
+			}()
+			r := <-done
+			if !r.hedge {
+				//主请求先返回，对冲请求在后台完成后归还连接
+				return r.resp, r.err
+			}
+			if r.err != nil {
+				//对冲请求失败，以主请求的结果为准
+				r = <-done
+				return r.resp, r.err
+			}
+			h.wins.Inc()
+			//对冲请求先返回，中断还没有结束的主请求，主请求结束后连接才可以继续使用
+			select {
+			case <-done:
+			default:
+				cc.SSDBClient.Interrupt()
+				<-done
+				//主请求可能在中断前已经结束，连接被半关闭，无论是否打开都关闭连接。
+				//不在返回结果前重新连接，下一个命令前或再次取出时重新打开
+				_ = cc.SSDBClient.Close()
+				cc.hedgeClosed = true
+			}
+			return r.resp, nil
+		}
+	}
+}
+
+// 从连接池块中直接取连接，不等待
+func (c *Connectors) tryClient(ctx context.Context) *Client {
+	if atomic.LoadInt32(&c.status) != consts.PoolStart || atomic.LoadInt32(&c.waitCount) > 0 {
+		//有等待者时不对冲，空闲连接先给等待者
+		return nil
+	}
+	startTime := time.Now().UnixNano()
+	cli, err := c.createClient()
+	if cli == nil || err != nil {
+		return nil
+	}
+	c.acquired(ctx, cli, startTime, false)
+	return cli
+}
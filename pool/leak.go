@@ -0,0 +1,231 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/seefan/gossdb/v2/client"
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/metrics"
+)
+
+// ErrLeakReclaimed returned by the commands of a client that was held too long and reclaimed by the leak detector
+//
+// 连接被持有太久并被泄漏检测强制回收后，再使用该连接时返回的错误
+var ErrLeakReclaimed = errors.New("gossdb client was held too long and reclaimed by the leak detector")
+
+// LeakInfo a client held longer than LeakDetectSecond
+//
+// 持有时间超过 LeakDetectSecond 的连接
+type LeakInfo struct {
+	//connection id
+	//连接 id
+	ConnID uint64
+	//position of the pool block
+	//连接池块的位置
+	Cell int
+	//acquisition time
+	//取出的时间
+	Since time.Time
+	//held time
+	//已持有的时间
+	Held time.Duration
+	//stack trace of the acquisition
+	//取出连接时的调用栈
+	Stack string
+}
+
+// 取出的连接
+type held struct {
+	since time.Time
+	pcs   []uintptr
+	//是否已经报告过
+	flagged bool
+}
+
+// 泄漏检测，记录取出连接的调用栈，持有时间超过阈值时报告，可选强制回收
+type leakDetector struct {
+	lock sync.Mutex
+	held map[*Client]*held
+	//阈值，0 不启用
+	threshold time.Duration
+	//是否强制回收
+	reclaim bool
+	//当前泄漏的个数
+	leaked int
+	//累计发现的泄漏数
+	found *metrics.Counter
+	//累计强制回收的个数
+	reclaimed *metrics.Counter
+}
+
+func newLeakDetector(cfg *conf.Config) *leakDetector {
+	return &leakDetector{
+		held:      make(map[*Client]*held),
+		threshold: time.Duration(cfg.LeakDetectSecond) * time.Second,
+		reclaim:   cfg.LeakReclaim,
+		found:     &metrics.Counter{},
+		reclaimed: &metrics.Counter{},
+	}
+}
+
+func (d *leakDetector) enabled() bool {
+	return d.threshold > 0
+}
+
+// 记录取出连接的调用栈
+func (d *leakDetector) acquire(cli *Client) {
+	pcs := make([]uintptr, 32)
+	pcs = pcs[:runtime.Callers(3, pcs)]
+	d.lock.Lock()
+	d.held[cli] = &held{since: time.Now(), pcs: pcs}
+	d.lock.Unlock()
+}
+
+// 归还连接，连接已被强制回收时返回 false
+func (d *leakDetector) release(cli *Client) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	h, ok := d.held[cli]
+	if !ok {
+		return false
+	}
+	if h.flagged {
+		d.leaked--
+	}
+	delete(d.held, cli)
+	return true
+}
+
+// 格式化调用栈
+func stack(pcs []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		sb.WriteString(f.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(f.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(f.Line))
+		sb.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+func (h *held) info(cli *Client, now time.Time) LeakInfo {
+	return LeakInfo{
+		ConnID: cli.ID(),
+		Cell:   int(cli.pool.index),
+		Since:  h.since,
+		Held:   now.Sub(h.since),
+		Stack:  stack(h.pcs),
+	}
+}
+
+// Leaks returns the clients held longer than LeakDetectSecond, empty if leak detection is not enabled
+//
+//	@return []LeakInfo
+//
+// 返回持有时间超过 LeakDetectSecond 的连接，没有启用泄漏检测时为空
+func (c *Connectors) Leaks() (re []LeakInfo) {
+	d := c.leaks
+	if !d.enabled() {
+		return nil
+	}
+	now := time.Now()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for cli, h := range d.held {
+		if now.Sub(h.since) >= d.threshold {
+			re = append(re, h.info(cli, now))
+		}
+	}
+	return
+}
+
+// 检查持有太久的连接，第一次发现时输出日志，开启强制回收时回收
+func (c *Connectors) checkLeaks(now time.Time) {
+	d := c.leaks
+	if !d.enabled() {
+		return
+	}
+	var found, reclaim []LeakInfo
+	var clients []*Client
+	d.lock.Lock()
+	for cli, h := range d.held {
+		if now.Sub(h.since) < d.threshold {
+			continue
+		}
+		if !h.flagged {
+			h.flagged = true
+			d.leaked++
+			d.found.Inc()
+			found = append(found, h.info(cli, now))
+		}
+		if d.reclaim {
+			if h.flagged {
+				d.leaked--
+			}
+			delete(d.held, cli)
+			reclaim = append(reclaim, h.info(cli, now))
+			clients = append(clients, cli)
+		}
+	}
+	d.lock.Unlock()
+	for _, l := range found {
+		c.log(slog.LevelWarn, "gossdb client held too long, possible leak", slog.Uint64("conn_id", l.ConnID), slog.Int("cell", l.Cell), slog.Duration("held", l.Held), slog.String("stack", l.Stack))
+	}
+	for i, cli := range clients {
+		c.reclaimLeak(cli, reclaim[i])
+	}
+}
+
+// 强制回收持有太久的连接，连接池块中换成新的连接。持有者可能正在使用原连接，这里只标记并中断正在执行的命令，
+// 原连接由持有者在下次执行命令或 Close 时关闭。原连接不再被连接池引用，如果持有者一直没有 Close 就被垃圾回收，将输出警告。
+// 没有强制回收的连接仍被连接池引用，不会被垃圾回收，只通过持有时间报告。
+func (c *Connectors) reclaimLeak(cli *Client, l LeakInfo) {
+	p := cli.pool
+	fresh, _ := c.newClient(p, false)
+	fresh.index = cli.index
+	atomic.StoreInt32(&cli.reclaimed, 1)
+	cli.SSDBClient.Interrupt()
+	atomic.AddInt32(&c.available, -1)
+	c.leaks.reclaimed.Inc()
+	c.log(slog.LevelWarn, "gossdb leaked client reclaimed", slog.Uint64("conn_id", l.ConnID), slog.Int("cell", l.Cell), slog.Duration("held", l.Held))
+	runtime.SetFinalizer(cli, func(cli *Client) {
+		c.log(slog.LevelWarn, "gossdb reclaimed client garbage collected without Close", slog.Uint64("conn_id", l.ConnID), slog.String("stack", l.Stack))
+	})
+	p.replace(fresh)
+	if atomic.LoadInt32(&c.waitCount) > 0 {
+		c.dispatch()
+	}
+}
+
+// 检查连接是否已被强制回收的中间件，在持有者的协程中关闭被回收的连接
+func (c *Connectors) leakMiddleware(cc *Client) client.Middleware {
+	return func(next client.Handler) client.Handler {
+		return func(ctx context.Context, cmd string, args ...interface{}) ([]string, error) {
+			if !cc.isReclaimed() {
+				resp, err := next(ctx, cmd, args...)
+				if err == nil || !cc.isReclaimed() {
+					return resp, err
+				}
+				//执行中被强制回收
+			}
+			cc.Error = ErrLeakReclaimed
+			_ = cc.SSDBClient.Close()
+			return nil, ErrLeakReclaimed
+		}
+	}
+}
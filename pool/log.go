@@ -0,0 +1,14 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+)
+
+// 输出连接池的生命周期日志，没有设置日志时忽略
+func (c *Connectors) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if c.config().Logger == nil {
+		return
+	}
+	c.config().Logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
@@ -0,0 +1,97 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/seefan/gossdb/v2/metrics"
+	"github.com/seefan/gossdb/v2/ssdbclient"
+)
+
+// 连接池的指标，计数器只增不减，不受 watchHealth 重置的影响
+type poolMetrics struct {
+	registry *metrics.Registry
+	//获取连接的耗时
+	acquireLatency *metrics.Histogram
+	//获取连接的次数
+	acquired *metrics.Counter
+	//需要等待的次数
+	waits *metrics.Counter
+	//等待超时的次数
+	timeouts *metrics.Counter
+	//归还时连接已关闭的次数
+	returnFails *metrics.Counter
+	//按命令统计的耗时
+	commandLatency *metrics.HistogramVec
+	//按类型统计的错误
+	errors *metrics.CounterVec
+	//按原因统计的回收连接数
+	recycled *metrics.CounterVec
+}
+
+func newPoolMetrics(c *Connectors) *poolMetrics {
+	m := &poolMetrics{
+		registry:       metrics.NewRegistry(),
+		acquireLatency: metrics.NewHistogram(),
+		acquired:       &metrics.Counter{},
+		waits:          &metrics.Counter{},
+		timeouts:       &metrics.Counter{},
+		returnFails:    &metrics.Counter{},
+		commandLatency: metrics.NewHistogramVec("command"),
+		errors:         metrics.NewCounterVec("kind"),
+		recycled:       metrics.NewCounterVec("reason"),
+	}
+	r := m.registry
+	r.Register("gossdb_pool_acquire_seconds", "Time taken to acquire a connection from the pool.", m.acquireLatency)
+	r.Register("gossdb_pool_acquired_total", "Number of connections acquired from the pool.", m.acquired)
+	r.Register("gossdb_pool_waits_total", "Number of acquisitions that had to wait for a connection.", m.waits)
+	r.Register("gossdb_pool_timeouts_total", "Number of acquisitions that timed out.", m.timeouts)
+	r.Register("gossdb_pool_return_failures_total", "Number of connections returned to the pool closed.", m.returnFails)
+	r.Register("gossdb_command_seconds", "Time taken to execute a command.", m.commandLatency)
+	r.Register("gossdb_errors_total", "Number of errors by kind.", m.errors)
+	r.Register("gossdb_pool_recycled_total", "Number of connections recycled by reason.", m.recycled)
+	r.Register("gossdb_pool_in_use", "Number of connections in use.", metrics.GaugeFunc(func() float64 {
+		return float64(atomic.LoadInt32(&c.available))
+	}))
+	r.Register("gossdb_pool_waiting", "Number of acquisitions waiting for a connection.", metrics.GaugeFunc(func() float64 {
+		return float64(atomic.LoadInt32(&c.waitCount))
+	}))
+	r.Register("gossdb_pool_cells", "Number of started pool blocks.", metrics.GaugeFunc(func() float64 {
+		return float64(atomic.LoadInt32(&c.cellPos))
+	}))
+	r.Register("gossdb_breaker_state", "State of the circuit breaker, 0 closed, 1 open, 2 half-open.", metrics.GaugeFunc(func() float64 {
+		return float64(c.breaker.State())
+	}))
+	r.Register("gossdb_breaker_trips_total", "Number of times the circuit breaker opened.", c.breaker.trips)
+	r.Register("gossdb_hedged_total", "Number of hedged reads.", c.hedger.hedged)
+	r.Register("gossdb_pool_leaks_total", "Number of clients held longer than the leak threshold.", c.leaks.found)
+	r.Register("gossdb_pool_leaks_reclaimed_total", "Number of leaked clients reclaimed.", c.leaks.reclaimed)
+	r.Register("gossdb_hedge_wins_total", "Number of hedged reads that returned first.", c.hedger.wins)
+	return m
+}
+
+// 记录获取连接的耗时
+func (m *poolMetrics) acquire(ts int64) {
+	m.acquired.Inc()
+	m.acquireLatency.Observe(time.Duration(ts).Seconds())
+}
+
+// 记录命令的耗时和错误，ssdb 返回的错误状态记为 server
+func (m *poolMetrics) observe(stat *ssdbclient.Stat) {
+	m.commandLatency.With(stat.Command()).Observe(stat.Duration.Seconds())
+	if stat.Err != nil {
+		m.errors.With(stat.ErrKind).Inc()
+	} else if len(stat.Resp) > 0 && stat.Resp[0] != "ok" && stat.Resp[0] != "not_found" {
+		m.errors.With("server").Inc()
+	}
+}
+
+// Metrics returns the metrics of the connection pool, it is a http.Handler of the prometheus text exposition format.
+// The counters are monotonic and not reset by the health watcher.
+//
+//	@return *metrics.Registry
+//
+// 返回连接池的指标，可以直接作为 http.Handler 以 prometheus 文本格式输出，计数器只增不减
+func (c *Connectors) Metrics() *metrics.Registry {
+	return c.metrics.registry
+}
@@ -0,0 +1,71 @@
+package pool
+
+import (
+	"context"
+	"time"
+
+	"github.com/seefan/gossdb/v2/slowlog"
+	"github.com/seefan/gossdb/v2/ssdbclient"
+)
+
+// AcquireStat statistics of a connection acquisition, passed to the observers
+//
+// 一次获取连接的统计信息，传递给观察者
+type AcquireStat struct {
+	//context of the acquisition
+	//获取连接的上下文
+	Ctx context.Context
+	//start time
+	//开始时间
+	Start time.Time
+	//duration
+	//耗时
+	Duration time.Duration
+	//whether waited for a released connection
+	//是否等待了其它连接的释放
+	Waited bool
+	//error
+	//错误
+	Err error
+}
+
+// Observer observes the connection acquisitions and the commands of the connection pool, e.g. for tracing
+//
+// 观察连接池的连接获取和命令执行，比如用于追踪
+type Observer interface {
+	//ObserveAcquire called after each acquisition
+	//每次获取连接后调用
+	ObserveAcquire(stat *AcquireStat)
+	//ObserveCommand called after each command
+	//每个命令执行后调用
+	ObserveCommand(stat *ssdbclient.Stat)
+}
+
+// AddObserver add an observer, it must be called before Start
+//
+//	@param o observer
+//
+// 添加一个观察者，必须在 Start 之前调用
+func (c *Connectors) AddObserver(o Observer) {
+	c.observers = append(c.observers, o)
+}
+
+// 命令执行后的回调，先统计指标再通知观察者
+func (c *Connectors) observe(stat *ssdbclient.Stat) {
+	c.metrics.observe(stat)
+	c.slowLog.Observe(stat)
+	c.recordBreaker(stat)
+	c.hedger.observe(stat)
+	for _, o := range c.observers {
+		o.ObserveCommand(stat)
+	}
+}
+
+// SlowLog returns the slow command log, the threshold is set by conf.Config.SlowLogMillisecond
+//
+//	@return *slowlog.Log
+//
+// 返回慢命令日志，阈值由 conf.Config.SlowLogMillisecond 设置
+func (c *Connectors) SlowLog() *slowlog.Log {
+	return c.slowLog
+}
@@ -2,6 +2,7 @@ package pool
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/seefan/gossdb/v2/consts"
 	"github.com/seefan/gossdb/v2/queue"
@@ -21,32 +22,49 @@ type Pool struct {
 	status int32
 	//new client
 	New func() (*Client, error)
-	//lock
+	//lock，无锁队列的 Get 和 Set 不加锁
 	lock sync.Mutex
 	//health 0 正常 1 检查 2 关闭中
 	health int32
+	//空闲索引是否使用无锁队列，是时 Get 和 Set 不加锁，检查空闲连接时逐个占用连接，不取空队列
+	lockFree bool
 }
 
-//新建一个池
-func newPool(size int) *Pool {
-	return &Pool{
-		pooled:    make([]*Client, size),
-		size:      size,
-		available: queue.NewQueue(size),
-		//available: newRing(size),
-		status: consts.None,
+//无锁队列模式下连接的状态
+const (
+	//空闲，索引在队列中
+	clientIdle int32 = iota
+	//被 Get 取出，索引不在队列中
+	clientOut
+	//空闲时被检查、预热或关闭占用，索引仍在队列中，Get 取到时放回队尾
+	clientHeld
+)
+
+//新建一个池，lockFree 为 true 时使用无锁的环形队列
+func newPool(size int, lockFree bool) *Pool {
+	p := &Pool{
+		pooled:   make([]*Client, size),
+		size:     size,
+		status:   consts.None,
+		lockFree: lockFree,
+	}
+	if lockFree {
+		p.available = newRing(size)
+	} else {
+		p.available = queue.NewQueue(size)
 	}
+	return p
 }
 
 // //CheckClose 检查是否可以关闭
 func (p *Pool) CheckClose() {
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	if p.status == consts.PoolStop {
+	if atomic.LoadInt32(&p.status) == consts.PoolStop {
 		p.Close()
 	}
-	if p.available.IsEmpty() && p.status != consts.PoolStop {
-		p.status = consts.PoolStop
+	if p.available.IsEmpty() && atomic.LoadInt32(&p.status) != consts.PoolStop {
+		atomic.StoreInt32(&p.status, consts.PoolStop)
 	}
 }
 
@@ -61,7 +79,7 @@ func (p *Pool) CheckHeath() {
 		}
 	}
 	if count == p.size {
-		p.status = consts.PoolStart
+		atomic.StoreInt32(&p.status, consts.PoolStart)
 	}
 }
 
@@ -71,6 +89,19 @@ func (p *Pool) CheckHeath() {
 //
 //启动连接
 func (p *Pool) Start() (err error) {
+	return p.start(true)
+}
+
+//StartLazy start the pool without opening the connections, they are opened when taken out
+//
+//  @return error possible error, operation successfully returned nil
+//
+//启动连接池但不打开连接，取出时再打开
+func (p *Pool) StartLazy() (err error) {
+	return p.start(false)
+}
+
+func (p *Pool) start(open bool) (err error) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 	for i := 0; i < p.size; i++ {
@@ -83,13 +114,13 @@ func (p *Pool) Start() (err error) {
 			cc.index = i
 			p.pooled[i] = cc
 		}
-		if !p.pooled[i].IsOpen() {
+		if open && !p.pooled[i].IsOpen() {
 			if err := p.pooled[i].Start(); err != nil {
 				return err
 			}
 		}
 	}
-	p.status = consts.PoolStart
+	atomic.StoreInt32(&p.status, consts.PoolStart)
 	return nil
 }
 
@@ -99,7 +130,7 @@ func (p *Pool) Start() (err error) {
 func (p *Pool) Close() {
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	p.status = consts.PoolStop
+	atomic.StoreInt32(&p.status, consts.PoolStop)
 	for _, c := range p.pooled {
 		if c != nil {
 			_ = c.SSDBClient.Close()
@@ -107,6 +138,34 @@ func (p *Pool) Close() {
 	}
 }
 
+//停止连接池块，空闲连接立即关闭，使用中的连接归还时关闭
+func (p *Pool) stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	atomic.StoreInt32(&p.status, consts.PoolStop)
+	if p.lockFree {
+		for _, c := range p.pooled {
+			if c != nil && p.hold(c) {
+				if c.IsOpen() {
+					_ = c.SSDBClient.Close()
+				}
+				atomic.StoreInt32(&c.state, clientIdle)
+			}
+		}
+		return
+	}
+	idle := make([]int, 0, p.available.Available())
+	for pos := p.available.Pop(); pos != -1; pos = p.available.Pop() {
+		idle = append(idle, pos)
+	}
+	for i := len(idle) - 1; i >= 0; i-- {
+		if c := p.pooled[idle[i]]; c != nil && c.IsOpen() {
+			_ = c.SSDBClient.Close()
+		}
+		p.available.Put(idle[i])
+	}
+}
+
 //Get get a pooled connection
 //
 //  @return *Client，client
@@ -114,9 +173,12 @@ func (p *Pool) Close() {
 //
 //获取一个缓存的连接
 func (p *Pool) Get() (client *Client) {
+	if p.lockFree {
+		return p.getLockFree()
+	}
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	if p.status == consts.PoolStop {
+	if atomic.LoadInt32(&p.status) == consts.PoolStop {
 		return nil
 	}
 	pos := p.available.Pop()
@@ -132,15 +194,144 @@ func (p *Pool) Get() (client *Client) {
 //
 //返还一个连接到连接池
 func (p *Pool) Set(client *Client) {
-	p.lock.Lock()
-	defer p.lock.Unlock()
 	if client == nil {
 		return
 	}
-	p.available.Put(client.index)
-	if p.status == consts.PoolStop {
+	if p.lockFree {
+		//被占用的空闲连接索引仍在队列中，只恢复状态
+		if !atomic.CompareAndSwapInt32(&client.state, clientHeld, clientIdle) {
+			atomic.StoreInt32(&client.state, clientIdle)
+			p.available.Put(client.index)
+		}
+	} else {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		p.available.Put(client.index)
+	}
+	if atomic.LoadInt32(&p.status) == consts.PoolStop {
 		if client.IsOpen() {
 			_ = client.SSDBClient.Close()
 		}
 	}
 }
+
+//无锁队列模式下取出连接，最多尝试 size 次，跳过被占用的连接
+func (p *Pool) getLockFree() *Client {
+	for i := 0; i < p.size; i++ {
+		if atomic.LoadInt32(&p.status) == consts.PoolStop {
+			return nil
+		}
+		pos := p.available.Pop()
+		if pos == -1 {
+			return nil
+		}
+		c := p.pooled[pos]
+		if atomic.CompareAndSwapInt32(&c.state, clientIdle, clientOut) {
+			return c
+		}
+		//连接正被检查或预热，放回队尾
+		p.available.Put(pos)
+	}
+	return nil
+}
+
+//无锁队列模式下占用一个空闲连接，索引留在队列中，用完后恢复为 clientIdle
+func (p *Pool) hold(c *Client) bool {
+	return atomic.CompareAndSwapInt32(&c.state, clientIdle, clientHeld)
+}
+
+//从空闲连接中取出指定位置的连接，不是空闲时返回 nil
+func (p *Pool) take(index int) (client *Client) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if atomic.LoadInt32(&p.status) == consts.PoolStop {
+		return nil
+	}
+	if p.lockFree {
+		//占用后由 Set 恢复状态
+		if c := p.pooled[index]; c != nil && p.hold(c) {
+			return c
+		}
+		return nil
+	}
+	idle := make([]int, 0, p.available.Available())
+	for pos := p.available.Pop(); pos != -1; pos = p.available.Pop() {
+		idle = append(idle, pos)
+	}
+	for i := len(idle) - 1; i >= 0; i-- {
+		if idle[i] == index {
+			client = p.pooled[index]
+		} else {
+			p.available.Put(idle[i])
+		}
+	}
+	return
+}
+
+//用新的连接替换同一位置的连接并放回空闲队列
+func (p *Pool) replace(client *Client) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.pooled[client.index] = client
+	p.available.Put(client.index)
+}
+
+//Recycle close the idle connections that fn returns true, they are reopened when taken out
+//
+//  @param fn check the idle connection
+//  @return int number of closed connections
+//
+//关闭空闲连接中 fn 返回 true 的连接，取出时再重新打开
+func (p *Pool) Recycle(fn func(c *Client) bool) (n int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if atomic.LoadInt32(&p.status) == consts.PoolStop {
+		return 0
+	}
+	if p.lockFree {
+		for _, c := range p.pooled {
+			if c != nil && p.hold(c) {
+				if c.IsOpen() && fn(c) {
+					n++
+				}
+				atomic.StoreInt32(&c.state, clientIdle)
+			}
+		}
+		return
+	}
+	idle := make([]int, 0, p.available.Available())
+	for pos := p.available.Pop(); pos != -1; pos = p.available.Pop() {
+		idle = append(idle, pos)
+	}
+	for i := len(idle) - 1; i >= 0; i-- {
+		if c := p.pooled[idle[i]]; c.IsOpen() && fn(c) {
+			n++
+		}
+		p.available.Put(idle[i])
+	}
+	return
+}
+
+//Stats returns the statistics of the pool block
+//
+//  @return CellStats
+//
+//返回连接池块的统计信息
+func (p *Pool) Stats() CellStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	s := CellStats{
+		Index:  int(p.index),
+		Size:   p.size,
+		Idle:   p.available.Available(),
+		Status: atomic.LoadInt32(&p.status),
+		Health: atomic.LoadInt32(&p.health),
+	}
+	s.InUse = s.Size - s.Idle
+	for _, c := range p.pooled {
+		if c != nil && c.IsOpen() {
+			s.Open++
+		}
+	}
+	return s
+}
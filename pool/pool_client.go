@@ -1,6 +1,10 @@
 package pool
 
 import (
+	"runtime"
+	"sync/atomic"
+	"time"
+
 	"github.com/seefan/gossdb/v2/client"
 )
 
@@ -17,6 +21,18 @@ type Client struct {
 	over *Connectors
 	//OpenTime open time
 	OpenTime int64
+	//当前连接打开后被取出的次数
+	uses int
+	//最后一次归还的时间
+	idleSince time.Time
+	//是否已被泄漏检测强制回收，由健康检查协程设置，持有者在下次使用时检查
+	reclaimed int32
+	//打开连接时使用的连接配置的版本
+	generation int32
+	//连接被对冲读主动关闭，下次使用时重新打开，归还时不作为失败
+	hedgeClosed bool
+	//无锁队列模式下连接的状态，clientIdle、clientOut 或 clientHeld
+	state int32
 }
 
 //Close put the client to Connectors
@@ -26,8 +42,22 @@ func (c *Client) Close() {
 	}
 }
 
+//是否已被泄漏检测强制回收
+func (c *Client) isReclaimed() bool {
+	return atomic.LoadInt32(&c.reclaimed) == 1
+}
+
 //Close put the client to Connectors
 func (c *Client) close() {
+	//等待异步命令完成，异步接口不再跟随连接回到连接池，出错时连接已被关闭
+	_ = c.Client.DetachAsync()
+	if c.isReclaimed() {
+		//已被强制回收，关闭后不再放回连接池
+		runtime.SetFinalizer(c, nil)
+		c.Error = ErrLeakReclaimed
+		_ = c.SSDBClient.Close()
+		return
+	}
 	if c.Error == nil && c.over != nil {
 		if c.used {
 			c.over.closeClient(c)
@@ -0,0 +1,122 @@
+package pool
+
+import (
+	"log/slog"
+	"math"
+	"sync/atomic"
+
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/consts"
+)
+
+// 当前的配置
+func (c *Connectors) config() *conf.Config {
+	return c.cfg.Load()
+}
+
+// 当前的连接池块列表，长度为曾经的最大块数
+func (c *Connectors) cells() []*Pool {
+	return *c.cell.Load()
+}
+
+// Reconfigure change the pool sizes, timeouts and credentials at runtime without restart.
+//
+// The sizes (MinPoolSize, MaxPoolSize, PoolSize, MaxWaitSize) take effect at once: the pool grows to the new minimum now,
+// and the cells above the new maximum are stopped at once: their idle connections are closed now, the connections in use when returned. The active cells keep their size,
+// only the cells created or reused later use the new PoolSize.
+// The connection settings (Host, Port, Password, timeouts, buffer sizes, Encoding) are applied by reopening the connections:
+// the idle connections are reopened when taken out, the connections in use when they are returned.
+// GetClientTimeout, HealthSecond, the lifetime limits and Logger are read from the new config directly.
+// The retry, circuit breaker, hedging, leak detection, slow log, LazyStart and LockFreeQueue settings can not be changed,
+// the values in cfg are ignored and the old values are kept.
+//
+//	@param cfg new config
+//	@return error the error of opening the cells for the new minimum, the new config is used anyway
+//
+// 运行中修改连接池的大小、超时和认证信息，不需要重启。
+//
+// 连接池大小（MinPoolSize、MaxPoolSize、PoolSize、MaxWaitSize）立即生效：连接池立即增长到新的最小值，超过新的最大值的连接池块立即停止：空闲连接立即关闭，使用中的连接在归还时关闭。
+// 正在使用的连接池块保持原来的大小，之后新建或重用的块才使用新的 PoolSize。
+// 连接配置（Host、Port、Password、超时、缓冲大小、Encoding）通过重新打开连接生效：空闲连接在取出时，使用中的连接在归还时重新打开。
+// GetClientTimeout、HealthSecond、连接寿命和 Logger 直接从新配置中读取。
+// 重试、熔断器、对冲读、泄漏检测、慢日志、LazyStart 和 LockFreeQueue 的配置不能修改，忽略 cfg 中的值，保持原值。
+func (c *Connectors) Reconfigure(cfg *conf.Config) error {
+	old := c.config()
+	nc := *cfg
+	nc.Default()
+	//不能修改的配置保持原值
+	if keepFixed(old, &nc) {
+		c.log(slog.LevelWarn, "gossdb pool reconfigure ignored the settings that can not be changed")
+	}
+	cellMax := int32(math.Floor(float64(nc.MaxPoolSize) / float64(nc.PoolSize)))
+	cellMin := int32(math.Floor(float64(nc.MinPoolSize) / float64(nc.PoolSize)))
+
+	c.resizeLock.Lock()
+	if cells := c.cells(); int(cellMax) > len(cells) {
+		grown := make([]*Pool, cellMax)
+		copy(grown, cells)
+		c.cell.Store(&grown)
+	}
+	reopen := connChanged(old, &nc)
+	c.cfg.Store(&nc)
+	if reopen {
+		atomic.AddInt32(&c.generation, 1)
+	}
+	atomic.StoreInt32(&c.cellMax, cellMax)
+	atomic.StoreInt32(&c.cellMin, cellMin)
+	atomic.StoreInt32(&c.maxWait, int32(nc.MaxWaitSize))
+	for {
+		pos := atomic.LoadInt32(&c.cellPos)
+		if pos <= cellMax || atomic.CompareAndSwapInt32(&c.cellPos, pos, cellMax) {
+			break
+		}
+	}
+	//超过新的最大块数的块立即停止，不再取出连接
+	for i, p := range c.cells() {
+		if int32(i) >= cellMax && p != nil && atomic.LoadInt32(&p.status) != consts.PoolStop {
+			p.stop()
+			c.log(slog.LevelDebug, "gossdb pool cell stopped", slog.Int("cell", i))
+		}
+	}
+	c.resizeLock.Unlock()
+	c.log(slog.LevelInfo, "gossdb pool reconfigured", slog.Int("min_cells", int(cellMin)), slog.Int("max_cells", int(cellMax)), slog.Int("size", nc.PoolSize), slog.Bool("reopen", reopen))
+
+	var err error
+	if atomic.LoadInt32(&c.status) == consts.PoolStart {
+		for atomic.LoadInt32(&c.cellPos) < cellMin && err == nil {
+			err = c.appendPool()
+		}
+		//新的连接池块交给等待者
+		c.dispatch()
+	}
+	return err
+}
+
+// 把启动后不能修改的配置恢复为原值，返回是否有配置被忽略
+func keepFixed(old, nc *conf.Config) bool {
+	changed := nc.RetryEnabled != old.RetryEnabled || nc.RetryMaxAttempts != old.RetryMaxAttempts ||
+		nc.RetryBackoffMillisecond != old.RetryBackoffMillisecond || nc.RetryMaxBackoffMillisecond != old.RetryMaxBackoffMillisecond ||
+		nc.BreakerFailures != old.BreakerFailures || nc.BreakerErrorRate != old.BreakerErrorRate ||
+		nc.BreakerMinRequests != old.BreakerMinRequests || nc.BreakerWindowSecond != old.BreakerWindowSecond ||
+		nc.BreakerOpenSecond != old.BreakerOpenSecond ||
+		nc.HedgePercentile != old.HedgePercentile || nc.HedgeMinDelayMillisecond != old.HedgeMinDelayMillisecond ||
+		nc.LeakDetectSecond != old.LeakDetectSecond || nc.LeakReclaim != old.LeakReclaim ||
+		nc.SlowLogMillisecond != old.SlowLogMillisecond || nc.SlowLogSize != old.SlowLogSize ||
+		nc.LazyStart != old.LazyStart || nc.LockFreeQueue != old.LockFreeQueue
+	nc.RetryEnabled, nc.RetryMaxAttempts = old.RetryEnabled, old.RetryMaxAttempts
+	nc.RetryBackoffMillisecond, nc.RetryMaxBackoffMillisecond = old.RetryBackoffMillisecond, old.RetryMaxBackoffMillisecond
+	nc.BreakerFailures, nc.BreakerErrorRate, nc.BreakerMinRequests = old.BreakerFailures, old.BreakerErrorRate, old.BreakerMinRequests
+	nc.BreakerWindowSecond, nc.BreakerOpenSecond = old.BreakerWindowSecond, old.BreakerOpenSecond
+	nc.HedgePercentile, nc.HedgeMinDelayMillisecond = old.HedgePercentile, old.HedgeMinDelayMillisecond
+	nc.LeakDetectSecond, nc.LeakReclaim = old.LeakDetectSecond, old.LeakReclaim
+	nc.SlowLogMillisecond, nc.SlowLogSize = old.SlowLogMillisecond, old.SlowLogSize
+	nc.LazyStart, nc.LockFreeQueue = old.LazyStart, old.LockFreeQueue
+	return changed
+}
+
+// 连接配置是否改变，改变后需要重新打开连接
+func connChanged(a, b *conf.Config) bool {
+	return a.Host != b.Host || a.Port != b.Port || a.Password != b.Password ||
+		a.ReadTimeout != b.ReadTimeout || a.WriteTimeout != b.WriteTimeout || a.ConnectTimeout != b.ConnectTimeout ||
+		a.ReadBufferSize != b.ReadBufferSize || a.WriteBufferSize != b.WriteBufferSize || a.Encoding != b.Encoding
+}
@@ -0,0 +1,52 @@
+package pool
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// 检查连接是否使用旧的连接配置，或超过寿命、空闲时间或使用次数，是时关闭连接并返回 true
+//
+// 空闲时间只在连接空闲时检查，归还时不检查
+func (c *Connectors) recycle(cli *Client, now time.Time, idle bool) bool {
+	cfg := c.config()
+	reason := ""
+	switch {
+	case cli.generation != atomic.LoadInt32(&c.generation):
+		reason = "reconfigured"
+	case cfg.MaxConnLifetime > 0 && now.Sub(cli.StartTime()) >= time.Duration(cfg.MaxConnLifetime)*time.Second:
+		reason = "lifetime"
+	case cfg.MaxUsesPerConn > 0 && cli.uses >= cfg.MaxUsesPerConn:
+		reason = "uses"
+	case idle && cfg.MaxIdleTime > 0 && !cli.idleSince.IsZero() && now.Sub(cli.idleSince) >= time.Duration(cfg.MaxIdleTime)*time.Second:
+		reason = "idle"
+	default:
+		return false
+	}
+	_ = cli.SSDBClient.Close()
+	cli.uses = 0
+	c.metrics.recycled.With(reason).Inc()
+	c.log(slog.LevelDebug, "gossdb connection recycled", slog.Uint64("conn_id", cli.ID()), slog.Int("cell", int(cli.pool.index)), slog.String("reason", reason))
+	return true
+}
+
+// 关闭已启动的连接池块中使用旧的连接配置、超过寿命或空闲时间的空闲连接
+func (c *Connectors) sweep(now time.Time) {
+	cfg := c.config()
+	if cfg.MaxConnLifetime <= 0 && cfg.MaxIdleTime <= 0 && atomic.LoadInt32(&c.generation) == 0 {
+		return
+	}
+	size := atomic.LoadInt32(&c.cellPos)
+	for i := int32(0); i < size; i++ {
+		if p := c.cells()[i]; p != nil {
+			p.Recycle(func(cli *Client) bool {
+				return c.recycle(cli, now, true)
+			})
+		}
+	}
+	//检查中被占用的连接会被 Get 跳过，检查后交给等待者
+	if atomic.LoadInt32(&c.waitCount) > 0 {
+		c.dispatch()
+	}
+}
@@ -10,90 +10,116 @@ import (
 	"sync/atomic"
 )
 
-//Ring ring slice
+// Ring lock-free ring of the available indexes, goroutine safe. Each slot has a sequence number telling whether it can be written or read,
+// so the writers and the readers only wait for each other when they meet at the same slot. Pop returns -1 only if it is empty.
+//
+// 无锁的可用索引环形队列，协程安全。每个位置有一个序号标识可写还是可读，读写只在同一个位置相遇时才互相等待，只有队列为空时 Pop 才返回 -1。
 type Ring struct {
-	value       []int32
-	read        uint32
+	slots []ringSlot
+	//下一个读取的位置
+	read uint32
+	//下一个写入的位置
 	write       uint32
-	maxRead     uint32
-	available   int32
-	capacity    uint32
 	capacityMod uint32
 }
 
+// 环形队列的一个位置，seq 等于写入位置时可写，等于写入位置加 1 时可读
+type ringSlot struct {
+	seq   uint32
+	value int32
+}
+
+// 创建一个环形队列，初始包含 0 到 vss-1 的索引
 func newRing(vss int) *Ring {
-	vs := make([]int, vss)
-	for i := 0; i < vss; i++ {
-		vs[i] = i
-	}
 	l := uint32(vss)
 	size := minQuantity(l)
-	if l == size {
-		size = l * 2
+	if size < 2 {
+		size = 2
 	}
 	r := &Ring{
-		capacity:    size,
-		capacityMod: size - 1,
-		value:       make([]int32, size),
-		maxRead:     l,
+		slots:       make([]ringSlot, size),
 		write:       l,
+		capacityMod: size - 1,
+	}
+	for i := range r.slots {
+		r.slots[i].seq = uint32(i)
 	}
-	for i, v := range vs {
-		r.value[i] = int32(v)
+	for i := uint32(0); i < l; i++ {
+		r.slots[i].value = int32(i)
+		r.slots[i].seq = i + 1
 	}
 	return r
 }
 
-//Pop get a index
+// Pop get a index
 //
-//  @return int index
+//	@return int index, -1 if empty
 //
-//获取一个可以连接的位置
+// 获取一个可以连接的位置，没有时返回 -1
 func (r *Ring) Pop() int {
 	read := atomic.LoadUint32(&r.read)
-	max := atomic.LoadUint32(&r.maxRead)
-	if read&r.capacityMod == max&r.capacityMod {
-		return -1
-	}
-	next := read + 1
-	v := atomic.LoadInt32(&r.value[read&r.capacityMod])
-	if atomic.CompareAndSwapUint32(&r.read, read, next) {
-		atomic.AddInt32(&r.available, -1)
-		return int(v)
+	for {
+		slot := &r.slots[read&r.capacityMod]
+		dif := int32(atomic.LoadUint32(&slot.seq) - (read + 1))
+		switch {
+		case dif == 0:
+			if atomic.CompareAndSwapUint32(&r.read, read, read+1) {
+				v := atomic.LoadInt32(&slot.value)
+				//可以在下一轮写入
+				atomic.StoreUint32(&slot.seq, read+r.capacityMod+1)
+				return int(v)
+			}
+			read = atomic.LoadUint32(&r.read)
+		case dif < 0:
+			if read == atomic.LoadUint32(&r.write) {
+				//队列为空
+				return -1
+			}
+			//写入位置已申请但还没有写完，等待写完
+			runtime.Gosched()
+			read = atomic.LoadUint32(&r.read)
+		default:
+			//已被其它调用读取
+			read = atomic.LoadUint32(&r.read)
+		}
 	}
-	return -1
 }
 
-//Put return a index
+// Put return a index
 //
-//  @param i index
-//  @return int pos
+//	@param i index
+//	@return int pos, -1 if full
 //
-//归还索引值
-func (r *Ring) Put(v int) {
-	var read, write, next, max uint32
+// 归还索引值，队列满时返回 -1
+func (r *Ring) Put(v int) int {
+	write := atomic.LoadUint32(&r.write)
 	for {
-		max = atomic.LoadUint32(&r.maxRead)
-		write = atomic.LoadUint32(&r.write)
-		if max != write { //不一致,表明有写请求尚未完成.这意味着,有写请求成功申请了空间但数据还没完全写进队列.所以如果有线程要读取,必须要等到写线程将数完全据写入到队列之后.
-			runtime.Gosched()
-			continue
-		}
-		read = atomic.LoadUint32(&r.read)
-		next = write + 1
-		if read&r.capacityMod == next&r.capacityMod { //空间满了
+		slot := &r.slots[write&r.capacityMod]
+		dif := int32(atomic.LoadUint32(&slot.seq) - write)
+		switch {
+		case dif == 0:
+			if atomic.CompareAndSwapUint32(&r.write, write, write+1) {
+				atomic.StoreInt32(&slot.value, int32(v))
+				//可以读取
+				atomic.StoreUint32(&slot.seq, write+1)
+				return int(write & r.capacityMod)
+			}
+			write = atomic.LoadUint32(&r.write)
+		case dif < 0:
+			if int32(write-atomic.LoadUint32(&r.read)) > int32(r.capacityMod) {
+				//队列已满
+				return -1
+			}
+			//上一轮的读取位置已申请但还没有释放，等待释放
 			runtime.Gosched()
-			continue
+			write = atomic.LoadUint32(&r.write)
+		default:
+			//已被其它调用写入
+			write = atomic.LoadUint32(&r.write)
 		}
-		if atomic.CompareAndSwapUint32(&r.write, write, next) {
-			atomic.StoreInt32(&r.value[write&r.capacityMod], int32(v))
-			atomic.StoreUint32(&r.maxRead, next)
-			atomic.AddInt32(&r.available, 1)
-			break
-		}
-		runtime.Gosched()
 	}
 }
+
 func minQuantity(v uint32) uint32 {
 	v--
 	v |= v >> 1
@@ -105,8 +131,16 @@ func minQuantity(v uint32) uint32 {
 	return v
 }
 
-//Available queue available size
+// IsEmpty check available index
+//
+//	@return bool
+func (r *Ring) IsEmpty() bool {
+	return r.Available() <= 0
+}
+
+// Available queue available size, including the slots being written
 func (r *Ring) Available() int {
-	c := atomic.LoadInt32(&r.available)
-	return int(c)
+	//先读 read，保证 write 不小于 read
+	read := atomic.LoadUint32(&r.read)
+	return int(int32(atomic.LoadUint32(&r.write) - read))
 }
@@ -0,0 +1,223 @@
+package pool
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/consts"
+	"github.com/seefan/gossdb/v2/queue"
+)
+
+func TestRing(t *testing.T) {
+	r := newRing(4)
+	if r.Available() != 4 || r.IsEmpty() {
+		t.Fatal(r.Available())
+	}
+	for i := 0; i < 4; i++ {
+		if v := r.Pop(); v != i {
+			t.Fatal(i, v)
+		}
+	}
+	if v := r.Pop(); v != -1 || !r.IsEmpty() {
+		t.Fatal(v)
+	}
+	for i := 0; i < 4; i++ {
+		if pos := r.Put(i); pos == -1 {
+			t.Fatal("full", i)
+		}
+	}
+	if pos := r.Put(4); pos != -1 {
+		t.Fatal("put to a full ring", pos)
+	}
+	if v := r.Pop(); v != 0 || r.Available() != 3 {
+		t.Fatal(v, r.Available())
+	}
+}
+
+func TestRingParallel(t *testing.T) {
+	const size = 20
+	r := newRing(size)
+	var held [size]int32
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10000; i++ {
+				//索引比协程多，总有可用的索引
+				v := r.Pop()
+				if v == -1 {
+					t.Error("ring is empty")
+					return
+				}
+				//同一个索引不能同时被两个调用取出
+				if !atomic.CompareAndSwapInt32(&held[v], 0, 1) {
+					t.Error("index popped twice", v)
+					return
+				}
+				atomic.StoreInt32(&held[v], 0)
+				if r.Put(v) == -1 {
+					t.Error("ring is full")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if r.Available() != size {
+		t.Fatal(r.Available())
+	}
+}
+
+// 加锁的队列，与 Pool 使用 queue.Queue 的方式一致
+type lockedQueue struct {
+	lock sync.Mutex
+	q    *queue.Queue
+}
+
+func (l *lockedQueue) Pop() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.q.Pop()
+}
+
+func (l *lockedQueue) Put(v int) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.q.Put(v)
+}
+
+func benchmarkAvailable(b *testing.B, a interface {
+	Pop() int
+	Put(int) int
+}) {
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if v := a.Pop(); v != -1 {
+				a.Put(v)
+			}
+		}
+	})
+}
+
+func BenchmarkAvailable_Queue(b *testing.B) {
+	benchmarkAvailable(b, &lockedQueue{q: queue.NewQueue(20)})
+}
+
+func BenchmarkAvailable_Ring(b *testing.B) {
+	benchmarkAvailable(b, newRing(20))
+}
+
+func benchmarkPool(b *testing.B, lockFree bool) {
+	p := newPool(20, lockFree)
+	for i := range p.pooled {
+		p.pooled[i] = &Client{index: i}
+	}
+	p.status = consts.PoolStart
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if c := p.Get(); c != nil {
+				p.Set(c)
+			}
+		}
+	})
+}
+
+func BenchmarkPool_Queue(b *testing.B) {
+	benchmarkPool(b, false)
+}
+
+func BenchmarkPool_Ring(b *testing.B) {
+	benchmarkPool(b, true)
+}
+
+func TestPoolRecycleParallel(t *testing.T) {
+	const size = 20
+	c := NewConnectors(&conf.Config{
+		Host:          "127.0.0.1",
+		Port:          8888,
+		PoolSize:      size,
+		MinPoolSize:   size,
+		MaxPoolSize:   size,
+		LockFreeQueue: true,
+	})
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p := c.cells()[0]
+	var held [size]int32
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				//连接比协程多，检查空闲连接时 Get 也不能取不到
+				cli := p.Get()
+				if cli == nil {
+					t.Error("no idle client during recycle")
+					return
+				}
+				if !atomic.CompareAndSwapInt32(&held[cli.index], 0, 1) {
+					t.Error("client taken twice", cli.index)
+					return
+				}
+				runtime.Gosched()
+				atomic.StoreInt32(&held[cli.index], 0)
+				p.Set(cli)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			p.Recycle(func(cli *Client) bool {
+				//检查期间让出，Get 同时执行
+				runtime.Gosched()
+				return false
+			})
+		}
+	}()
+	wg.Wait()
+	<-done
+	if s := p.Stats(); s.Idle != size {
+		t.Fatal(s.Idle)
+	}
+}
+
+func TestPoolTakeLockFree(t *testing.T) {
+	p := newPool(2, true)
+	for i := range p.pooled {
+		p.pooled[i] = &Client{index: i}
+	}
+	p.status = consts.PoolStart
+	//被占用的连接索引仍在队列中，Get 跳过它
+	held := p.take(0)
+	if held == nil || p.take(0) != nil {
+		t.Fatal("take")
+	}
+	if c := p.Get(); c == nil || c.index != 1 {
+		t.Fatal(c)
+	}
+	if c := p.Get(); c != nil {
+		t.Fatal("got the held client", c.index)
+	}
+	//归还被占用的连接只恢复状态，索引不会重复
+	p.Set(held)
+	if c := p.Get(); c == nil || c.index != 0 {
+		t.Fatal(c)
+	}
+	if c := p.Get(); c != nil {
+		t.Fatal("index put twice", c.index)
+	}
+	if n := p.available.Available(); n != 0 {
+		t.Fatal(n)
+	}
+}
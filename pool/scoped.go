@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"context"
+
+	"github.com/seefan/gossdb/v2/client"
+)
+
+// With acquire a connection, run fn with it and always release it, including on panic.
+// The connection is closed before release if fn panics or leaves it in an error state, it is reopened when taken out next time.
+// The client must not be used after fn returns.
+//
+//	@param ctx context of the acquisition and the commands
+//	@param fn function using the client
+//	@return error the acquisition error or the error returned by fn
+//
+// 取出一个连接执行 fn，执行后总是归还连接，包括 panic 时。
+// 如果 fn 发生 panic 或者连接处于错误状态，归还前关闭连接，下次取出时重新打开。fn 返回后不能再使用该连接。
+func (c *Connectors) With(ctx context.Context, fn func(c *client.Client) error) (err error) {
+	cli, err := c.NewClientContext(ctx)
+	if err != nil {
+		return err
+	}
+	//由这里负责归还，不能在命令后自动回收
+	cli.AutoClose = false
+	defer func() {
+		if e := recover(); e != nil {
+			c.release(cli, true)
+			panic(e)
+		}
+		c.release(cli, cli.Error != nil || !cli.SSDBClient.IsOpen())
+	}()
+	return fn(&cli.Client)
+}
+
+// Do acquire a connection, execute a command and release the connection
+//
+//	@param cmd command name
+//	@param args arguments after the command
+//	@return []string response
+//	@return error possible error, operation successfully returned nil
+//
+// 取出一个连接执行一个命令后归还
+func (c *Connectors) Do(cmd string, args ...interface{}) ([]string, error) {
+	return c.DoContext(context.Background(), cmd, args...)
+}
+
+// DoContext acquire a connection with the context, execute a command and release the connection, see Do
+//
+//	@param ctx context
+//	@param cmd command name
+//	@param args arguments after the command
+//	@return []string response
+//	@return error possible error, operation successfully returned nil
+//
+// 使用上下文取出一个连接执行一个命令后归还
+func (c *Connectors) DoContext(ctx context.Context, cmd string, args ...interface{}) (resp []string, err error) {
+	err = c.With(ctx, func(cc *client.Client) error {
+		resp, err = cc.Do(append([]interface{}{cmd}, args...)...)
+		return err
+	})
+	return
+}
+
+// 归还连接，broken 为 true 时先关闭连接
+func (c *Connectors) release(cli *Client, broken bool) {
+	if broken {
+		_ = cli.SSDBClient.Close()
+		cli.Error = nil
+	}
+	cli.close()
+}
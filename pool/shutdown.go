@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/seefan/gossdb/v2/consts"
+)
+
+// ErrPoolClosed returned when acquiring a client from a pool that is shut down or closed, the waiters get it too
+//
+// 连接池正在关闭或已关闭时获取连接返回的错误，正在等待的调用也返回这个错误
+var ErrPoolClosed = errors.New("gossdb pool is closed")
+
+// Shutdown gracefully shut down the pool: new acquisitions and the waiters fail with ErrPoolClosed at once,
+// the clients in use can still execute commands until they are returned. When all clients are returned or the context is done,
+// the connections are closed and the background goroutines are stopped.
+//
+//	@param ctx context, limits the time waiting for the clients in use and the background goroutines
+//	@return error ctx.Err() if the context is done before the pool is drained, the pool is closed anyway
+//
+// 优雅地关闭连接池：新的获取和正在等待的调用立即返回 ErrPoolClosed，使用中的连接在归还前仍可以执行命令。
+// 所有连接归还或上下文结束后，关闭所有连接并停止后台协程。
+func (c *Connectors) Shutdown(ctx context.Context) (err error) {
+	if !atomic.CompareAndSwapInt32(&c.status, consts.PoolStart, consts.PoolStopping) {
+		//没有启动或已经关闭
+		c.Close()
+		return nil
+	}
+	atomic.StoreInt32(&c.closed, 1)
+	c.waiters.closeAll()
+	c.log(slog.LevelInfo, "gossdb pool shutting down", slog.Int("in_use", int(atomic.LoadInt32(&c.available))))
+	tick := time.NewTicker(10 * time.Millisecond)
+	for atomic.LoadInt32(&c.available) > 0 && err == nil {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-tick.C:
+		}
+	}
+	tick.Stop()
+	if err != nil {
+		c.log(slog.LevelWarn, "gossdb pool shutdown timed out, closing the clients in use", slog.Int("in_use", int(atomic.LoadInt32(&c.available))))
+	}
+	c.Close()
+	//等待后台协程退出
+	stopped := make(chan struct{})
+	go func() {
+		c.background.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	c.log(slog.LevelInfo, "gossdb pool shut down")
+	return err
+}
+
+// 通知后台协程退出
+func (c *Connectors) stopBackground() {
+	c.doneLock.Lock()
+	defer c.doneLock.Unlock()
+	if c.done == nil {
+		return
+	}
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+}
@@ -0,0 +1,155 @@
+package pool
+
+import (
+	"sync/atomic"
+)
+
+// CellStats statistics of a pool block
+//
+// 连接池块的统计信息
+type CellStats struct {
+	//position in the connection pool
+	//在连接池中的位置
+	Index int
+	//number of connections
+	//连接数
+	Size int
+	//number of connections in use
+	//使用中的连接数
+	InUse int
+	//number of idle connections
+	//空闲的连接数
+	Idle int
+	//number of open connections
+	//打开的连接数
+	Open int
+	//status, see consts.PoolStart, consts.PoolStop, consts.PoolCheck
+	//状态，见 consts.PoolStart, consts.PoolStop, consts.PoolCheck
+	Status int32
+	//health, 0 normal, consts.PoolCheck to check
+	//健康状态，0 正常，consts.PoolCheck 需要检查
+	Health int32
+}
+
+// Stats statistics of the connection pool. The lifetime counters are monotonic and not reset by the health watcher.
+//
+// 连接池的统计信息，累计计数只增不减，不受 watchHealth 重置的影响
+type Stats struct {
+	//number of started pool blocks, the current cellPos
+	//已启动的连接池块个数，即当前的 cellPos
+	CellPos int
+	//minimum number of pool blocks
+	//连接池块的最小个数
+	CellMin int
+	//maximum number of pool blocks
+	//连接池块的最大个数
+	CellMax int
+	//number of connections in the started blocks
+	//已启动的连接池块中的连接数
+	Total int
+	//number of connections in use
+	//使用中的连接数
+	InUse int
+	//number of idle connections in the started blocks
+	//已启动的连接池块中空闲的连接数
+	Idle int
+	//number of acquisitions waiting for a connection
+	//等待连接的个数
+	Waiting int
+	//number of queued waiters of each priority, indexed by Priority
+	//每个优先级排队等待的个数，下标为 Priority
+	WaitingByPriority []int
+	//maximum number of waiters
+	//最大等待数
+	MaxWait int
+	//details of each created pool block, including the stopped ones
+	//每个已创建的连接池块的信息，包括已停止的
+	Cells []CellStats
+	//lifetime number of acquired connections
+	//累计获取连接的次数
+	Acquired uint64
+	//lifetime number of acquisitions that had to wait
+	//累计需要等待的次数
+	Waits uint64
+	//lifetime number of acquisitions that timed out
+	//累计等待超时的次数
+	Timeouts uint64
+	//lifetime number of acquisitions rejected because too many waiters
+	//累计因等待数过多被拒绝的次数
+	Rejected uint64
+	//lifetime number of connections returned closed
+	//累计归还时连接已关闭的次数
+	ReturnFailures uint64
+	//lifetime number of connections recycled for MaxConnLifetime, MaxIdleTime, MaxUsesPerConn or Reconfigure
+	//累计因超过寿命、空闲时间、使用次数或修改连接配置而回收的连接数
+	Recycled uint64
+	//state of the circuit breaker
+	//熔断器的状态
+	Breaker BreakerState
+	//lifetime number of times the circuit breaker opened
+	//累计熔断器打开的次数
+	BreakerTrips uint64
+	//lifetime number of acquisitions rejected by the circuit breaker
+	//累计被熔断器拒绝的次数
+	BreakerRejected uint64
+	//number of clients held longer than LeakDetectSecond and not reclaimed
+	//当前持有时间超过 LeakDetectSecond 且没有被回收的连接数
+	Leaked int
+	//lifetime number of leaks found
+	//累计发现的泄漏数
+	LeaksFound uint64
+	//lifetime number of leaked clients reclaimed
+	//累计强制回收的泄漏连接数
+	LeaksReclaimed uint64
+	//lifetime number of hedged reads
+	//累计发起对冲读的次数
+	Hedged uint64
+	//lifetime number of hedged reads that returned first
+	//累计对冲读先返回的次数
+	HedgeWins uint64
+}
+
+// Stats returns the statistics of the connection pool
+//
+//	@return Stats
+//
+// 返回连接池的统计信息
+func (c *Connectors) Stats() Stats {
+	s := Stats{
+		CellPos:           int(atomic.LoadInt32(&c.cellPos)),
+		CellMin:           int(atomic.LoadInt32(&c.cellMin)),
+		CellMax:           int(atomic.LoadInt32(&c.cellMax)),
+		InUse:             int(atomic.LoadInt32(&c.available)),
+		Waiting:           int(atomic.LoadInt32(&c.waitCount)),
+		WaitingByPriority: c.waiters.lens(),
+		MaxWait:           int(atomic.LoadInt32(&c.maxWait)),
+		Acquired:          c.metrics.acquired.Value(),
+		Waits:             c.metrics.waits.Value(),
+		Timeouts:          c.metrics.timeouts.Value(),
+		Rejected:          c.metrics.errors.With("pool_busy").Value(),
+		ReturnFailures:    c.metrics.returnFails.Value(),
+		Breaker:           c.breaker.State(),
+		BreakerTrips:      c.breaker.trips.Value(),
+		BreakerRejected:   c.metrics.errors.With("breaker_open").Value(),
+		Recycled:          c.metrics.recycled.With("lifetime").Value() + c.metrics.recycled.With("idle").Value() + c.metrics.recycled.With("uses").Value() + c.metrics.recycled.With("reconfigured").Value(),
+		LeaksFound:        c.leaks.found.Value(),
+		LeaksReclaimed:    c.leaks.reclaimed.Value(),
+		Hedged:            c.hedger.hedged.Value(),
+		HedgeWins:         c.hedger.wins.Value(),
+	}
+	c.leaks.lock.Lock()
+	s.Leaked = c.leaks.leaked
+	c.leaks.lock.Unlock()
+	for i, p := range c.cells() {
+		if p == nil {
+			continue
+		}
+		cs := p.Stats()
+		if i < s.CellPos {
+			s.Total += cs.Size
+			s.Idle += cs.Idle
+		}
+		s.Cells = append(s.Cells, cs)
+	}
+	return s
+}
@@ -0,0 +1,181 @@
+package pool
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Priority priority of a waiting acquisition, the waiters of a higher priority are served first, FIFO within the same priority
+//
+// 等待连接的优先级，高优先级的先获得连接，同一优先级内先到先得
+type Priority int
+
+const (
+	//PriorityHigh request-path traffic
+	//高优先级，用于请求路径上的调用
+	PriorityHigh Priority = iota
+	//PriorityNormal default priority
+	//默认优先级
+	PriorityNormal
+	//PriorityLow background jobs and batches
+	//低优先级，用于后台任务和批处理
+	PriorityLow
+	//优先级的个数
+	priorities
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context with the priority of the acquisitions using it, e.g. NewClientContext
+//
+//	@param ctx parent context
+//	@param p priority
+//	@return context.Context
+//
+// 返回带有优先级的上下文，使用它获取连接时按该优先级排队
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// 上下文中的优先级，默认为 PriorityNormal
+func priorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p >= PriorityHigh && p < priorities {
+		return p
+	}
+	return PriorityNormal
+}
+
+// 等待者，连接通过 ch 交给等待者，连接池关闭时收到 nil
+type waiter struct {
+	ch       chan *Client
+	priority Priority
+	elem     *list.Element
+}
+
+// 等待队列，每个优先级一个先进先出队列
+type waitQueue struct {
+	lock  sync.Mutex
+	lanes [priorities]list.List
+	//连接池关闭后不再排队
+	closed bool
+}
+
+// 加入队列，front 为 true 时排在同一优先级的队首，调用前需要加锁
+func (q *waitQueue) push(p Priority, front bool) *waiter {
+	w := &waiter{ch: make(chan *Client, 1), priority: p}
+	if q.closed {
+		//连接池已关闭，立即收到 nil
+		w.ch <- nil
+		return w
+	}
+	if front {
+		w.elem = q.lanes[p].PushFront(w)
+	} else {
+		w.elem = q.lanes[p].PushBack(w)
+	}
+	return w
+}
+
+// 是否没有等待者，调用前需要加锁
+func (q *waitQueue) empty() bool {
+	for i := range q.lanes {
+		if q.lanes[i].Len() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 取出优先级最高、等待最久的等待者，调用前需要加锁
+func (q *waitQueue) pop() *waiter {
+	for i := range q.lanes {
+		if e := q.lanes[i].Front(); e != nil {
+			q.lanes[i].Remove(e)
+			w := e.Value.(*waiter)
+			w.elem = nil
+			return w
+		}
+	}
+	return nil
+}
+
+// 从队列中移除，已经被取出时返回 false
+func (q *waitQueue) remove(w *waiter) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if w.elem == nil {
+		return false
+	}
+	q.lanes[w.priority].Remove(w.elem)
+	w.elem = nil
+	return true
+}
+
+// 每个优先级等待的个数
+func (q *waitQueue) lens() []int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	re := make([]int, len(q.lanes))
+	for i := range q.lanes {
+		re[i] = q.lanes[i].Len()
+	}
+	return re
+}
+
+// 通知所有等待者连接池已关闭
+func (q *waitQueue) closeAll() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	for w := q.pop(); w != nil; w = q.pop() {
+		w.ch <- nil
+	}
+}
+
+// 连接池启动后允许排队
+func (q *waitQueue) open() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = false
+}
+
+// 加入等待队列，队列为空时先在锁内再取一次空闲连接，避免连接在加入前归还而等待者一直等待。
+// front 为 true 时排在队首，用于分到的连接检查失败后重新等待。取到的连接还没有检查，需要在锁外调用 prepareClient。
+func (c *Connectors) enqueue(ctx context.Context, front bool) (*waiter, *Client) {
+	c.waiters.lock.Lock()
+	defer c.waiters.lock.Unlock()
+	if !front && c.waiters.empty() {
+		for i := 0; i < 2; i++ {
+			if cli := c.takeClient(i); cli != nil {
+				return nil, cli
+			}
+		}
+	}
+	return c.waiters.push(priorityFrom(ctx), front), nil
+}
+
+// 把空闲连接按优先级和先后顺序交给等待者，锁内只取连接，连接的检查由等待者在锁外完成
+func (c *Connectors) dispatch() {
+	c.waiters.lock.Lock()
+	defer c.waiters.lock.Unlock()
+	for !c.waiters.empty() {
+		var cli *Client
+		for i := 0; i < 2 && cli == nil; i++ {
+			cli = c.takeClient(i)
+		}
+		if cli == nil {
+			return
+		}
+		c.waiters.pop().ch <- cli
+	}
+}
+
+// 归还连接，有等待者时交给等待者
+func (c *Connectors) put(cli *Client) {
+	cli.pool.Set(cli)
+	if atomic.LoadInt32(&c.waitCount) > 0 {
+		c.dispatch()
+	}
+}
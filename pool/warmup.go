@@ -0,0 +1,157 @@
+package pool
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/seefan/gossdb/v2/consts"
+)
+
+// WarmUpProgress progress of the warm-up, in lazy mode the connections of MinPoolSize are opened in the background
+//
+// 预热进度，延迟启动时 MinPoolSize 个连接在后台打开
+type WarmUpProgress struct {
+	//number of connections to open
+	//需要打开的连接数
+	Total int
+	//number of opened connections
+	//已打开的连接数
+	Opened int
+	//number of connections failed to open, they are opened again when taken out
+	//打开失败的连接数，取出时会再次打开
+	Failed int
+	//whether the warm-up is done
+	//预热是否完成
+	Done bool
+	//the last error
+	//最后一个错误
+	Err error
+}
+
+// 预热状态
+type warmUp struct {
+	lock     sync.Mutex
+	progress WarmUpProgress
+	//预热完成时关闭
+	ready chan struct{}
+	//进度回调
+	callbacks []func(p WarmUpProgress)
+}
+
+// OnWarmUp add a callback of the warm-up progress, it is called after each connection is opened or failed,
+// and once more with Done set when the warm-up finishes, before Ready is closed. It must be called before Start.
+//
+//	@param fn callback
+//
+// 添加预热进度的回调，每个连接打开或失败后调用，预热完成时在 Ready 关闭前再用 Done 为 true 的进度调用一次，必须在 Start 之前调用
+func (c *Connectors) OnWarmUp(fn func(p WarmUpProgress)) {
+	c.warm.callbacks = append(c.warm.callbacks, fn)
+}
+
+// WarmUpProgress returns the progress of the warm-up
+//
+//	@return WarmUpProgress
+//
+// 返回预热进度
+func (c *Connectors) WarmUpProgress() WarmUpProgress {
+	c.warm.lock.Lock()
+	defer c.warm.lock.Unlock()
+	return c.warm.progress
+}
+
+// Ready returns a channel closed when the warm-up is done, it is closed when Start returns if not in lazy mode
+//
+//	@return <-chan struct{}
+//
+// 返回预热完成时关闭的通道，非延迟启动时 Start 返回前关闭
+func (c *Connectors) Ready() <-chan struct{} {
+	c.warm.lock.Lock()
+	defer c.warm.lock.Unlock()
+	if c.warm.ready == nil {
+		c.warm.ready = make(chan struct{})
+	}
+	return c.warm.ready
+}
+
+// 开始预热，重置进度
+func (c *Connectors) startWarmUp() {
+	c.warm.lock.Lock()
+	defer c.warm.lock.Unlock()
+	if c.warm.ready == nil || c.warm.progress.Done {
+		c.warm.ready = make(chan struct{})
+	}
+	c.warm.progress = WarmUpProgress{Total: int(atomic.LoadInt32(&c.cellMin)) * c.config().PoolSize}
+}
+
+// 更新预热进度
+func (c *Connectors) warmUpStep(err error) {
+	c.warm.lock.Lock()
+	if err != nil {
+		c.warm.progress.Failed++
+		c.warm.progress.Err = err
+	} else {
+		c.warm.progress.Opened++
+	}
+	p := c.warm.progress
+	c.warm.lock.Unlock()
+	for _, fn := range c.warm.callbacks {
+		fn(p)
+	}
+}
+
+// 预热完成
+func (c *Connectors) warmUpDone(err error) {
+	c.warm.lock.Lock()
+	if err == nil && !c.config().LazyStart {
+		c.warm.progress.Opened = c.warm.progress.Total
+	}
+	if err != nil {
+		c.warm.progress.Err = err
+	}
+	c.warm.progress.Done = true
+	p := c.warm.progress
+	ready := c.warm.ready
+	c.warm.lock.Unlock()
+	c.log(slog.LevelInfo, "gossdb pool warmed up", slog.Int("total", p.Total), slog.Int("opened", p.Opened), slog.Int("failed", p.Failed))
+	//两种模式都用最终进度通知一次，回调完成后再关闭 ready
+	for _, fn := range c.warm.callbacks {
+		fn(p)
+	}
+	close(ready)
+}
+
+// 在后台逐个打开前 cellMin 个连接池块中空闲的连接，失败的连接在取出时再次打开
+func (c *Connectors) warmUp() {
+	var last error
+	for i := int32(0); i < atomic.LoadInt32(&c.cellMin); i++ {
+		p := c.cells()[i]
+		if p == nil {
+			continue
+		}
+		for j := 0; j < p.size; j++ {
+			if atomic.LoadInt32(&c.status) != consts.PoolStart {
+				c.warmUpDone(last)
+				return
+			}
+			cli := p.take(j)
+			if cli == nil {
+				//正在使用中，取出时已经打开
+				c.warmUpStep(nil)
+				continue
+			}
+			var err error
+			if !cli.SSDBClient.IsOpen() {
+				if err = cli.SSDBClient.Start(); err != nil {
+					last = err
+					c.breaker.record(err, time.Now())
+					c.log(slog.LevelWarn, "gossdb warm-up connection failed", slog.Int("cell", int(i)), slog.String("error", err.Error()))
+				}
+			}
+			c.put(cli)
+			c.warmUpStep(err)
+		}
+	}
+	c.warmUpDone(nil)
+}
@@ -24,7 +24,7 @@ func NewQueue(size int) *Queue {
 
 //Available queue available size
 func (q *Queue) Available() int {
-	return q.size - q.pos
+	return q.pos + 1
 }
 
 //IsEmpty check available index
@@ -49,3 +49,24 @@ func TestQueue_Pop(t *testing.T) {
 		t.Log(pos, q.value)
 	}
 }
+
+func TestQueue_Available(t *testing.T) {
+	q := NewQueue(5)
+	if q.Available() != 5 {
+		t.Error("available err", q.Available())
+	}
+	for i := 4; i >= 0; i-- {
+		q.Pop()
+		if q.Available() != i {
+			t.Error("available err", i, q.Available())
+		}
+	}
+	if !q.IsEmpty() {
+		t.Error("not empty")
+	}
+	q.Put(0)
+	q.Put(1)
+	if q.Available() != 2 {
+		t.Error("available err", q.Available())
+	}
+}
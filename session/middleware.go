@@ -0,0 +1,82 @@
+package session
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// Middleware net/http middleware, the session is loaded before the handler and saved before the response header is written.
+// Use FromContext to get the session in the handler.
+//
+//	@param next the handler
+//	@return http.Handler
+//
+// net/http 中间件，在处理前加载会话，在写入响应头之前保存会话。处理函数中使用 FromContext 获取会话。
+func (s *Store) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := s.Load(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		sw := &writer{ResponseWriter: w, store: s, session: session}
+		next.ServeHTTP(sw, r.WithContext(NewContext(r.Context(), session)))
+		sw.save()
+	})
+}
+
+// 在写入响应头之前保存会话
+type writer struct {
+	http.ResponseWriter
+	store   *Store
+	session *Session
+	//是否已保存
+	saved bool
+}
+
+func (w *writer) save() {
+	if !w.saved {
+		w.saved = true
+		//保存失败时会话数据丢失，但不影响本次响应
+		_ = w.store.Save(w.ResponseWriter, w.session)
+	}
+}
+
+func (w *writer) WriteHeader(code int) {
+	w.save()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(bs []byte) (int, error) {
+	w.save()
+	return w.ResponseWriter.Write(bs)
+}
+
+func (w *writer) Flush() {
+	w.save()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack save the session and hijack the connection, used by websocket
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.save()
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *writer) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the original response writer, used by http.ResponseController
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,149 @@
+// Package session http session store for net/http, backed by ssdb
+//
+// 基于 ssdb 的 net/http 会话存储
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// Session http session
+//
+// 会话，非协程安全
+type Session struct {
+	//session id
+	//会话 id
+	id string
+	//重新生成 id 前的旧 id，保存时删除
+	oldID string
+	//values
+	//会话中保存的值
+	values map[string]interface{}
+	//是否为新建的会话
+	isNew bool
+	//值是否被修改过
+	modified bool
+	//是否已经销毁
+	destroyed bool
+}
+
+// 会话在 context 中的 key
+type contextKey struct{}
+
+func newSession() (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		id:     id,
+		values: make(map[string]interface{}),
+		isNew:  true,
+	}, nil
+}
+
+// ID returns the session id
+func (s *Session) ID() string {
+	return s.id
+}
+
+// IsNew returns true if the session is not stored yet
+//
+// 是否为新建的会话
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+// Get returns the value of the key
+//
+//	@param key the key
+//	@return interface{} the value, nil if not exists
+//
+// 获取指定 key 的值，不存在时返回 nil
+func (s *Session) Get(key string) interface{} {
+	return s.values[key]
+}
+
+// Set set the value of the key
+//
+//	@param key the key
+//	@param value the value, must be serializable by the encoder
+//
+// 设置指定 key 的值，值必须可以被序列化
+func (s *Session) Set(key string, value interface{}) {
+	s.values[key] = value
+	s.modified = true
+}
+
+// Delete delete the key
+//
+// 删除指定的 key
+func (s *Session) Delete(key string) {
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// Values returns all values of the session
+//
+// 返回会话中所有的值
+func (s *Session) Values() map[string]interface{} {
+	return s.values
+}
+
+// Regenerate regenerate the session id, the values are kept. It should be called on login.
+//
+//	@return error possible error, operation successfully returned nil
+//
+// 重新生成会话 id，保留会话中的值，登录时应调用，防止会话固定攻击
+func (s *Session) Regenerate() error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	if s.oldID == "" && !s.isNew {
+		s.oldID = s.id
+	}
+	s.id = id
+	s.modified = true
+	return nil
+}
+
+// Destroy mark the session as destroyed, it will be removed when saved
+//
+// 标记会话为销毁状态，保存时将删除
+func (s *Session) Destroy() {
+	s.destroyed = true
+}
+
+// FromContext returns the session stored in the context by the middleware
+//
+//	@param ctx context
+//	@return *Session the session, nil if not exists
+//
+// 返回中间件保存在 context 中的会话
+func FromContext(ctx context.Context) *Session {
+	if s, ok := ctx.Value(contextKey{}).(*Session); ok {
+		return s
+	}
+	return nil
+}
+
+// NewContext returns a new context with the session
+//
+// 返回一个包含会话的 context
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// 生成随机的会话 id
+func newID() (string, error) {
+	bs := make([]byte, 32)
+	if _, err := rand.Read(bs); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bs), nil
+}
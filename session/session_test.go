@@ -0,0 +1,134 @@
+package session
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+func TestMiddleware(t *testing.T) {
+	p := pool.NewConnectors(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	store := NewStore(p)
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := FromContext(r.Context())
+		switch r.URL.Path {
+		case "/login":
+			if err := s.Regenerate(); err != nil {
+				t.Error(err)
+			}
+			s.Set("user", "seefan")
+		case "/logout":
+			s.Destroy()
+		}
+		if v, ok := s.Get("user").(string); ok {
+			_, _ = w.Write([]byte(v))
+		}
+	}))
+	do := func(path string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+		req := httptest.NewRequest("GET", path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		cs := rec.Result().Cookies()
+		if len(cs) == 0 {
+			return rec, nil
+		}
+		return rec, cs[0]
+	}
+	//不使用会话的匿名请求不保存会话
+	if _, anonymous := do("/", nil); anonymous != nil {
+		t.Error("unused session is saved")
+	}
+	_, login := do("/login", nil)
+	if login == nil {
+		t.Fatal("no cookie")
+	}
+	rec, _ := do("/", login)
+	if rec.Body.String() != "seefan" {
+		t.Errorf("session value is %q", rec.Body.String())
+	}
+	_, logout := do("/logout", login)
+	if logout == nil || logout.MaxAge >= 0 {
+		t.Error("cookie is not cleared")
+	}
+	rec, _ = do("/", login)
+	if rec.Body.String() != "" {
+		t.Errorf("session is not destroyed, value is %q", rec.Body.String())
+	}
+	//不能反序列化的会话当作不存在，不返回错误
+	_, login = do("/login", nil)
+	c, err := p.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AutoClose = false
+	defer c.Close()
+	if err = c.Set(store.Prefix+login.Value, "not json"); err != nil {
+		t.Fatal(err)
+	}
+	rec, _ = do("/", login)
+	if rec.Code != http.StatusOK || rec.Body.String() != "" {
+		t.Errorf("corrupted session: %d %q", rec.Code, rec.Body.String())
+	}
+	if ok, err := c.Exists(store.Prefix + login.Value); err != nil || ok {
+		t.Error("corrupted session is not removed", err)
+	}
+}
+
+func TestMiddleware_hijack(t *testing.T) {
+	p := pool.NewConnectors(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	store := NewStore(p)
+	srv := httptest.NewServer(store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("hijacker is not forwarded")
+			return
+		}
+		conn, rw, err := h.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\n\r\nhijacked")
+		_ = rw.Flush()
+	})))
+	defer srv.Close()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != 8 {
+		t.Fatal(resp.Status, resp.ContentLength)
+	}
+}
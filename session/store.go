@@ -0,0 +1,197 @@
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/seefan/goerr"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+// Store session store
+//
+// 会话存储，会话数据保存在 ssdb 中，key 为 Prefix 加上会话 id
+type Store struct {
+	//key prefix. Default: "session:"
+	//ssdb 中 key 的前缀，默认为 "session:"
+	Prefix string
+	//cookie name. Default: "gossdb_session"
+	//cookie 的名称，默认为 "gossdb_session"
+	CookieName string
+	//session lifetime, sliding with each request. Default: 30 minutes
+	//会话的有效期，每次访问会重新计算。默认值: 30分钟
+	MaxAge time.Duration
+	//cookie path. Default: "/"
+	//cookie 的路径，默认为 "/"
+	Path string
+	//cookie domain
+	//cookie 的域名
+	Domain string
+	//cookie secure
+	Secure bool
+	//cookie http only. Default: true
+	HTTPOnly bool
+	//cookie same site
+	SameSite http.SameSite
+	//The session values are serialized by this function, default is the EncodingFunc of the connection pool
+	//会话数据的序列化函数，默认使用连接池的 EncodingFunc
+	EncodingFunc func(v interface{}) []byte
+	//The session values are deserialized by this function, default is json.Unmarshal
+	//会话数据的反序列化函数，默认使用 json.Unmarshal
+	DecodingFunc func(data []byte, v interface{}) error
+	//连接池
+	pool *pool.Connectors
+}
+
+// NewStore create a session store with the default options
+//
+//	@param p connection pool
+//	@return *Store
+//
+// 使用连接池创建一个会话存储
+func NewStore(p *pool.Connectors) *Store {
+	return &Store{
+		Prefix:       "session:",
+		CookieName:   "gossdb_session",
+		MaxAge:       30 * time.Minute,
+		Path:         "/",
+		HTTPOnly:     true,
+		SameSite:     http.SameSiteLaxMode,
+		EncodingFunc: p.EncodingFunc,
+		DecodingFunc: json.Unmarshal,
+		pool:         p,
+	}
+}
+
+// 取一个连接，多个命令共用，使用完后需要关闭
+func (s *Store) client() (*pool.Client, error) {
+	c, err := s.pool.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	c.AutoClose = false
+	return c, nil
+}
+
+func (s *Store) ttl() int64 {
+	return int64(s.MaxAge / time.Second)
+}
+
+// Load load the session of the request, a new session is returned if not exists, expired or cannot be decoded.
+// The lifetime of an existing session is extended, the data that cannot be decoded is removed.
+//
+//	@param r the request
+//	@return *Session
+//	@return error possible error, operation successfully returned nil
+//
+// 加载请求对应的会话，如果不存在、已过期或不能反序列化就返回一个新的会话。已存在的会话会延长有效期，不能反序列化的数据将被删除。
+func (s *Store) Load(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(s.CookieName)
+	if err != nil || cookie.Value == "" {
+		return newSession()
+	}
+	c, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	key := s.Prefix + cookie.Value
+	v, err := c.Get(key)
+	if err != nil {
+		return nil, goerr.Errorf(err, "load session %s error", cookie.Value)
+	}
+	if v.IsEmpty() {
+		return newSession()
+	}
+	values := make(map[string]interface{})
+	if err = s.DecodingFunc(v.Bytes(), &values); err != nil {
+		//数据损坏或序列化格式已修改，和不存在一样处理，旧数据直接删除
+		_ = c.Del(key)
+		return newSession()
+	}
+	if _, err = c.Expire(key, s.ttl()); err != nil {
+		return nil, goerr.Errorf(err, "expire session %s error", cookie.Value)
+	}
+	return &Session{
+		id:     cookie.Value,
+		values: values,
+	}, nil
+}
+
+// Save save the session and write the cookie. A destroyed session is removed, and the old id of a regenerated session is removed.
+// A new session is stored only after it is modified, so the requests that never use the session do not write to ssdb.
+//
+//	@param w the response writer
+//	@param session the session
+//	@return error possible error, operation successfully returned nil
+//
+// 保存会话并写入 cookie。已销毁的会话将被删除，重新生成 id 的会话将删除旧的 id。
+// 新会话只有在修改后才保存，不使用会话的请求不会写入 ssdb。
+func (s *Store) Save(w http.ResponseWriter, session *Session) error {
+	if !session.modified && !session.destroyed && session.oldID == "" {
+		//没有修改过的新会话不需要保存，也不写入 cookie；已存在的会话只刷新 cookie 的有效期
+		if !session.isNew {
+			http.SetCookie(w, s.cookie(session.id, int(s.ttl())))
+		}
+		return nil
+	}
+	c, err := s.client()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if session.oldID != "" {
+		if err = c.Del(s.Prefix + session.oldID); err != nil {
+			return goerr.Errorf(err, "delete session %s error", session.oldID)
+		}
+		session.oldID = ""
+	}
+	if session.destroyed {
+		if !session.isNew {
+			if err = c.Del(s.Prefix + session.id); err != nil {
+				return goerr.Errorf(err, "delete session %s error", session.id)
+			}
+		}
+		http.SetCookie(w, s.cookie("", -1))
+		return nil
+	}
+	if session.modified {
+		bs := s.EncodingFunc(session.values)
+		if bs == nil {
+			return goerr.String("session values cannot be serialized, please check EncodingFunc")
+		}
+		if err = c.Set(s.Prefix+session.id, bs, s.ttl()); err != nil {
+			return goerr.Errorf(err, "save session %s error", session.id)
+		}
+		session.isNew = false
+		session.modified = false
+	}
+	http.SetCookie(w, s.cookie(session.id, int(s.ttl())))
+	return nil
+}
+
+// Destroy remove the session and clear the cookie
+//
+//	@param w the response writer
+//	@param session the session
+//	@return error possible error, operation successfully returned nil
+//
+// 删除会话并清除 cookie
+func (s *Store) Destroy(w http.ResponseWriter, session *Session) error {
+	session.Destroy()
+	return s.Save(w, session)
+}
+
+func (s *Store) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     s.CookieName,
+		Value:    value,
+		Path:     s.Path,
+		Domain:   s.Domain,
+		MaxAge:   maxAge,
+		Secure:   s.Secure,
+		HttpOnly: s.HTTPOnly,
+		SameSite: s.SameSite,
+	}
+}
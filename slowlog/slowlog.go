@@ -0,0 +1,217 @@
+// Package slowlog bounded in-memory log of the slow commands
+//
+// 慢命令日志，保存在内存中，超过容量时覆盖最早的记录
+package slowlog
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/seefan/gossdb/v2/ssdbclient"
+)
+
+const (
+	//每个参数最多保留的长度
+	maxArgLen = 64
+	//最多保留的参数个数
+	maxArgs = 16
+)
+
+// Entry a slow command
+//
+// 一条慢命令记录
+type Entry struct {
+	//start time
+	//开始时间
+	Time time.Time
+	//command name
+	//命令名称
+	Command string
+	//arguments after the command, truncated
+	//命令后面的参数，过长的会被截断
+	Args []string
+	//duration
+	//耗时
+	Duration time.Duration
+	//bytes sent
+	//发送的字节数
+	BytesSent int64
+	//bytes received
+	//接收的字节数
+	BytesReceived int64
+	//connection id
+	//连接 id
+	ConnID uint64
+	//error message, empty if no error
+	//错误信息，没有错误时为空
+	Err string
+}
+
+// Log slow command log, goroutine safe
+//
+// 慢命令日志，协程安全
+type Log struct {
+	lock sync.Mutex
+	//环形缓冲
+	entries []Entry
+	//下一个写入的位置
+	next int
+	//已写入的总数
+	total uint64
+	//慢命令的阈值
+	threshold time.Duration
+	//可选的日志
+	logger *slog.Logger
+}
+
+// New create a slow log
+//
+//	@param size maximum number of entries
+//	@param threshold the commands slower than this value are recorded, 0 to disable
+//	@param logger optional logger, the slow commands are also logged to it at warn level
+//	@return *Log
+//
+// 创建一个慢日志
+func New(size int, threshold time.Duration, logger *slog.Logger) *Log {
+	if size < 1 {
+		size = 1
+	}
+	return &Log{
+		entries:   make([]Entry, size),
+		threshold: threshold,
+		logger:    logger,
+	}
+}
+
+// Threshold returns the threshold of the slow commands
+func (l *Log) Threshold() time.Duration {
+	return l.threshold
+}
+
+// Observe record the command if it is slow, it can be used as ssdbclient.SSDBClient.Observer
+//
+//	@param stat statistics of the command
+//
+// 如果命令执行时间超过阈值就记录下来，可以作为 ssdbclient.SSDBClient.Observer 使用
+func (l *Log) Observe(stat *ssdbclient.Stat) {
+	if l.threshold <= 0 || stat.Duration < l.threshold {
+		return
+	}
+	e := Entry{
+		Time:          stat.Start,
+		Command:       stat.Command(),
+		Args:          truncate(stat.Args),
+		Duration:      stat.Duration,
+		BytesSent:     stat.BytesSent,
+		BytesReceived: stat.BytesReceived,
+		ConnID:        stat.ConnID,
+	}
+	if stat.Err != nil {
+		e.Err = stat.Err.Error()
+	}
+	l.lock.Lock()
+	l.entries[l.next] = e
+	l.next = (l.next + 1) % len(l.entries)
+	l.total++
+	l.lock.Unlock()
+	if l.logger != nil {
+		l.logger.LogAttrs(stat.Ctx, slog.LevelWarn, "gossdb slow command",
+			slog.String("command", e.Command),
+			slog.Any("args", e.Args),
+			slog.Duration("duration", e.Duration),
+			slog.Int64("bytes_sent", e.BytesSent),
+			slog.Int64("bytes_received", e.BytesReceived),
+			slog.Uint64("conn_id", e.ConnID),
+			slog.String("error", e.Err),
+		)
+	}
+}
+
+// Entries returns the recorded slow commands, the newest first
+//
+//	@return []Entry
+//
+// 返回记录的慢命令，最新的在前
+func (l *Log) Entries() []Entry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	size := l.len()
+	re := make([]Entry, size)
+	for i := 0; i < size; i++ {
+		re[i] = l.entries[(l.next-1-i+len(l.entries))%len(l.entries)]
+	}
+	return re
+}
+
+// Len returns the number of the recorded slow commands
+//
+// 返回当前保存的慢命令条数
+func (l *Log) Len() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.len()
+}
+
+// Total returns the number of the slow commands since created or reset
+//
+// 返回创建或重置以来慢命令的总数
+func (l *Log) Total() uint64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.total
+}
+
+// Reset remove all recorded slow commands
+//
+// 清空慢日志
+func (l *Log) Reset() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for i := range l.entries {
+		l.entries[i] = Entry{}
+	}
+	l.next = 0
+	l.total = 0
+}
+
+func (l *Log) len() int {
+	if l.total < uint64(len(l.entries)) {
+		return int(l.total)
+	}
+	return len(l.entries)
+}
+
+// 参数转换为字符串并截断
+func truncate(args []interface{}) []string {
+	if len(args) < 2 {
+		return nil
+	}
+	args = args[1:]
+	size := len(args)
+	if size > maxArgs {
+		size = maxArgs
+	}
+	re := make([]string, 0, size+1)
+	for _, arg := range args[:size] {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		default:
+			s = fmt.Sprint(v)
+		}
+		if len(s) > maxArgLen {
+			s = s[:maxArgLen] + "...(" + strconv.Itoa(len(s)) + " bytes)"
+		}
+		re = append(re, s)
+	}
+	if len(args) > size {
+		re = append(re, "...("+strconv.Itoa(len(args)-size)+" more arguments)")
+	}
+	return re
+}
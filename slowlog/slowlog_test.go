@@ -0,0 +1,84 @@
+package slowlog
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seefan/gossdb/v2/ssdbclient"
+)
+
+func stat(cmd string, d time.Duration, args ...interface{}) *ssdbclient.Stat {
+	return &ssdbclient.Stat{
+		Args:     append([]interface{}{cmd}, args...),
+		Start:    time.Now(),
+		Duration: d,
+		ConnID:   7,
+	}
+}
+
+func TestLog(t *testing.T) {
+	l := New(3, 10*time.Millisecond, nil)
+	l.Observe(stat("get", time.Millisecond, "fast"))
+	if l.Len() != 0 {
+		t.Fatal("fast command recorded")
+	}
+	for i := 0; i < 5; i++ {
+		l.Observe(stat("get", 20*time.Millisecond, i))
+	}
+	if l.Len() != 3 || l.Total() != 5 {
+		t.Fatal("len", l.Len(), "total", l.Total())
+	}
+	es := l.Entries()
+	for i, want := range []string{"4", "3", "2"} {
+		if es[i].Args[0] != want || es[i].Command != "get" || es[i].ConnID != 7 {
+			t.Fatal(i, es[i])
+		}
+	}
+	l.Reset()
+	if l.Len() != 0 || len(l.Entries()) != 0 {
+		t.Fatal("not reset")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	args := []interface{}{"multi_set"}
+	for i := 0; i < maxArgs+4; i++ {
+		args = append(args, strings.Repeat("x", 100))
+	}
+	re := truncate(args)
+	if len(re) != maxArgs+1 {
+		t.Fatal(len(re))
+	}
+	if re[0] != strings.Repeat("x", maxArgLen)+"...(100 bytes)" {
+		t.Fatal(re[0])
+	}
+	if re[maxArgs] != "...(4 more arguments)" {
+		t.Fatal(re[maxArgs])
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	l := New(3, 0, nil)
+	l.Observe(stat("get", time.Second, "a"))
+	if l.Len() != 0 {
+		t.Fatal("disabled log recorded")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(3, time.Millisecond, slog.New(slog.NewTextHandler(&buf, nil)))
+	s := stat("hget", time.Second, "h", "k")
+	s.Err = errors.New("timeout")
+	l.Observe(s)
+	out := buf.String()
+	for _, want := range []string{"gossdb slow command", "command=hget", "conn_id=7", "error=timeout"} {
+		if !strings.Contains(out, want) {
+			t.Fatal(want, out)
+		}
+	}
+}
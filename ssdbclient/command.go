@@ -0,0 +1,27 @@
+package ssdbclient
+
+// read-only commands, they can be sent to another connection or a replica
+//
+// 只读命令，可以发送到其它连接或从库
+var readOnly = map[string]bool{
+	"ping": true, "version": true, "dbsize": true, "info": true,
+	"get": true, "exists": true, "ttl": true, "getbit": true, "bitcount": true, "countbit": true,
+	"strlen": true, "substr": true, "keys": true, "rkeys": true, "scan": true, "rscan": true, "multi_get": true,
+	"hget": true, "hexists": true, "hsize": true, "hgetall": true, "hkeys": true, "hscan": true, "hrscan": true,
+	"hlist": true, "hrlist": true, "multi_hget": true,
+	"zget": true, "zexists": true, "zsize": true, "zrank": true, "zrrank": true, "zrange": true, "zrrange": true,
+	"zkeys": true, "zscan": true, "zrscan": true, "zcount": true, "zsum": true, "zavg": true,
+	"zlist": true, "zrlist": true, "multi_zget": true,
+	"qsize": true, "qfront": true, "qback": true, "qget": true, "qrange": true, "qslice": true,
+	"qlist": true, "qrlist": true,
+}
+
+// IsReadOnly whether the command only reads data
+//
+//	@param cmd command name
+//	@return bool
+//
+// 命令是否只读
+func IsReadOnly(cmd string) bool {
+	return readOnly[cmd]
+}
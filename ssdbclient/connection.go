@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -27,6 +28,8 @@ type connection struct {
 	host string
 	//connection
 	sock *net.TCPConn
+	//保护 sock 的替换，用于其它协程中断读取。使用指针，client.NewClient 会复制 SSDBClient
+	sockLock *sync.Mutex
 	//readBuf
 	buf []byte
 	//write buf
@@ -50,6 +53,36 @@ type connection struct {
 	encodingFunc func(v interface{}) []byte
 	//dialer
 	dialer *net.Dialer
+	//bytes sent since last reset
+	//发送的字节数
+	sent int64
+	//bytes received since last reset
+	//接收的字节数
+	received int64
+	//the writer under bufw, it counts the bytes written to the socket
+	//bufw 写入的 socket，统计实际写入的字节数。使用指针，复制 SSDBClient 后仍然统计到同一个计数
+	writer *sockWriter
+}
+
+// 统计实际写入 socket 的字节数，用于判断请求是否已经发出
+type sockWriter struct {
+	sock *net.TCPConn
+	//实际写入 socket 的字节数
+	flushed int64
+}
+
+func (w *sockWriter) Write(p []byte) (int, error) {
+	n, err := w.sock.Write(p)
+	w.flushed += int64(n)
+	return n, err
+}
+
+// 实际写入 socket 的字节数
+func (c *connection) flushed() int64 {
+	if c.writer == nil {
+		return 0
+	}
+	return c.writer.flushed
 }
 
 const delim int = 1
@@ -76,9 +109,17 @@ func (c *connection) start() error {
 	if err != nil {
 		return err
 	}
-	c.bufw = bufio.NewWriterSize(sock, c.writeBufferSize*1024*2)
+	c.writer = &sockWriter{sock: sock}
+	c.bufw = bufio.NewWriterSize(c.writer, c.writeBufferSize*1024*2)
 	c.buf = make([]byte, c.readBufferSize*1024)
+	c.resetParse()
+	if c.sockLock == nil {
+		//没有使用 NewSSDBClient 创建的连接
+		c.sockLock = new(sync.Mutex)
+	}
+	c.sockLock.Lock()
 	c.sock = sock
+	c.sockLock.Unlock()
 	return nil
 }
 
@@ -87,16 +128,23 @@ func (c *connection) start() error {
 //	@return error that may occur on shutdown. Return nil if successful shutdown
 func (c *connection) close() error {
 	c.buf = nil
-	//received data
-	c.rsp = nil
-	//pos list
-	c.posList = nil
+	c.resetParse()
 	if c.sock == nil {
 		return nil
 	}
 	return c.sock.Close()
 }
 
+// 清除解析到一半的响应，读取出错后剩余的数据无法再和请求对应
+func (c *connection) resetParse() {
+	c.rsp = nil
+	c.rspLen = 0
+	c.posList = nil
+	c.pos = 0
+	c.nextPos = 0
+	c.dataSize = 0
+}
+
 // write write to buf
 func (c *connection) writeBytes(bs []byte) error {
 	lbs := strconv.AppendInt(nil, int64(len(bs)), 10)
@@ -112,6 +160,7 @@ func (c *connection) writeBytes(bs []byte) error {
 	if err := c.bufw.WriteByte(endN); err != nil {
 		return err
 	}
+	c.sent += int64(len(lbs) + len(bs) + 2)
 	return nil
 }
 
@@ -184,6 +233,7 @@ func (c *connection) send(args []interface{}) (err error) {
 	if err := c.bufw.WriteByte(endN); err != nil {
 		return err
 	}
+	c.sent++
 	if err := c.sock.SetWriteDeadline(time.Now().Add(time.Second * time.Duration(c.writeTimeout))); err != nil {
 		return err
 	}
@@ -196,7 +246,18 @@ func (c *connection) send(args []interface{}) (err error) {
 
 // 第一层，读取数据流
 func (c *connection) recv() (resp []string, err error) {
+	defer func() {
+		if err != nil {
+			c.resetParse()
+		}
+	}()
 	isEnd := false
+	//上次读取时多读的数据，流水线发送时可能包含后续命令的响应
+	if c.rspLen > 0 {
+		if isEnd, err = c.parseBlock(); err != nil {
+			return nil, err
+		}
+	}
 	for !isEnd {
 		//设置读取数据超时，
 		if err = c.sock.SetReadDeadline(time.Now().Add(time.Second * time.Duration(c.readTimeout))); err != nil {
@@ -209,6 +270,7 @@ func (c *connection) recv() (resp []string, err error) {
 		if n < 1 {
 			break
 		}
+		c.received += int64(n)
 		c.rsp = append(c.rsp, c.buf[:n]...)
 		c.rspLen += n
 		if c.rspLen >= c.nextPos { //保证可以有解析数据
@@ -216,19 +278,22 @@ func (c *connection) recv() (resp []string, err error) {
 			if err != nil {
 				return nil, err
 			}
-			if isEnd {
-				max := len(c.posList) / 2
-				resp = make([]string, max)
-				for i := 0; i < max; i++ {
-					resp[i] = string(c.rsp[c.posList[i*2]:c.posList[i*2+1]])
-				}
-				break
-			}
 		}
 	}
-
-	c.rsp = nil
-	c.rspLen = 0
+	if isEnd {
+		max := len(c.posList) / 2
+		resp = make([]string, max)
+		for i := 0; i < max; i++ {
+			resp[i] = string(c.rsp[c.posList[i*2]:c.posList[i*2+1]])
+		}
+	}
+	if isEnd && c.pos < c.rspLen {
+		c.rsp = append([]byte(nil), c.rsp[c.pos:c.rspLen]...)
+		c.rspLen = len(c.rsp)
+	} else {
+		c.rsp = nil
+		c.rspLen = 0
+	}
 	c.posList = nil
 	c.pos = 0
 	c.nextPos = 0
@@ -266,6 +331,8 @@ func index(pos, size int, c byte, bs []byte) int {
 func (c *connection) parseData(n int) (end bool, err error) {
 	if c.dataSize == 0 {
 		if c.pos == n {
+			//空行，响应结束
+			c.pos = n + 1
 			return true, nil
 		}
 		ds, e := strconv.Atoi(string(c.rsp[c.pos:n]))
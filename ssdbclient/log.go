@@ -0,0 +1,18 @@
+package ssdbclient
+
+import (
+	"log/slog"
+)
+
+// 输出连接的生命周期日志，没有设置日志时忽略
+func (s *SSDBClient) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if s.logger == nil {
+		return
+	}
+	attrs = append(attrs,
+		slog.Uint64("conn_id", s.id),
+		slog.String("host", s.host),
+		slog.Int("port", s.port),
+	)
+	s.logger.LogAttrs(s.Context(), level, msg, attrs...)
+}
@@ -0,0 +1,177 @@
+package ssdbclient
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/seefan/gossdb/v2/conf"
+)
+
+// idempotent commands, retrying them after the request was written does not apply them twice
+//
+// 幂等命令，请求发出后重试也不会重复生效
+var idempotent = map[string]bool{
+	"auth": true, "ping": true, "version": true, "dbsize": true, "info": true,
+	"get": true, "set": true, "setx": true, "del": true, "exists": true, "expire": true, "ttl": true,
+	"getbit": true, "bitcount": true, "countbit": true, "strlen": true, "substr": true,
+	"keys": true, "rkeys": true, "scan": true, "rscan": true,
+	"multi_get": true, "multi_set": true, "multi_del": true,
+	"hget": true, "hset": true, "hdel": true, "hexists": true, "hsize": true, "hclear": true,
+	"hgetall": true, "hkeys": true, "hscan": true, "hrscan": true, "hlist": true, "hrlist": true,
+	"multi_hget": true, "multi_hset": true, "multi_hdel": true,
+	"zget": true, "zset": true, "zdel": true, "zexists": true, "zsize": true, "zclear": true,
+	"zrank": true, "zrrank": true, "zrange": true, "zrrange": true, "zkeys": true, "zscan": true, "zrscan": true,
+	"zcount": true, "zsum": true, "zavg": true, "zlist": true, "zrlist": true, "zremrangebyscore": true,
+	"multi_zget": true, "multi_zset": true, "multi_zdel": true,
+	"qsize": true, "qfront": true, "qback": true, "qget": true, "qset": true, "qrange": true, "qslice": true,
+	"qclear": true, "qlist": true, "qrlist": true,
+}
+
+// IsIdempotent whether the command can be applied more than once with the same result.
+// Counters, queue pushes and pops, getset, setnx, setbit, zremrangebyrank and the unknown commands are not idempotent.
+//
+//	@param cmd command name
+//	@return bool
+//
+// 命令是否幂等，计数器、队列的出入队、getset、setnx、setbit、zremrangebyrank 以及未知的命令都不是幂等的
+func IsIdempotent(cmd string) bool {
+	return idempotent[cmd]
+}
+
+// IsRetryable whether the error is worth a retry, only the connection errors are retryable
+//
+//	@param kind error kind of the attempt, see Stat.ErrKind
+//	@param err error of the attempt
+//	@return bool
+//
+// 错误是否值得重试，只有连接相关的错误才会重试
+func IsRetryable(kind string, err error) bool {
+	switch kind {
+	case "closed", "connect", "send", "recv", "timeout":
+		return true
+	}
+	return false
+}
+
+// RetryPolicy retry policy of the failed commands
+//
+// 命令失败时的重试策略
+type RetryPolicy struct {
+	//maximum number of attempts, including the first one
+	//最多执行的次数，包括第一次
+	MaxAttempts int
+	//backoff before the first retry, doubled for each following retry
+	//第一次重试前等待的时间，之后每次翻倍
+	Backoff time.Duration
+	//maximum backoff
+	//最长的等待时间
+	MaxBackoff time.Duration
+	//fraction of the backoff randomized to avoid retry storms, between 0 and 1
+	//等待时间中随机的比例，避免同时重试，取值 0 到 1
+	Jitter float64
+	//classify the retryable errors, IsRetryable if nil
+	//判断错误是否可以重试，为空时使用 IsRetryable
+	Retryable func(kind string, err error) bool
+	//classify the idempotent commands, IsIdempotent if nil
+	//判断命令是否幂等，为空时使用 IsIdempotent
+	Idempotent func(cmd string) bool
+}
+
+// NewRetryPolicy create the retry policy from the config, nil if retry is not enabled, MaxAttempts is at least 2 if enabled
+//
+//	@param cfg config
+//	@return *RetryPolicy
+//
+// 使用配置创建重试策略，没有启用重试时返回 nil，启用时 MaxAttempts 至少为 2
+func NewRetryPolicy(cfg *conf.Config) *RetryPolicy {
+	if !cfg.RetryEnabled {
+		return nil
+	}
+	attempts := cfg.RetryMaxAttempts
+	if attempts < 2 {
+		//启用重试时至少重试一次，没有经过 Default 的配置也是如此
+		attempts = 2
+	}
+	return &RetryPolicy{
+		MaxAttempts: attempts,
+		Backoff:     time.Duration(cfg.RetryBackoffMillisecond) * time.Millisecond,
+		MaxBackoff:  time.Duration(cfg.RetryMaxBackoffMillisecond) * time.Millisecond,
+		Jitter:      0.5,
+	}
+}
+
+// ShouldRetry whether to retry after the failed attempt.
+// The commands that are not idempotent are only retried when the request was not written.
+//
+//	@param attempt number of the attempts done
+//	@param cmd command name
+//	@param kind error kind of the attempt
+//	@param written whether any byte of the request was written to the connection
+//	@param err error of the attempt
+//	@return bool
+//
+// 失败后是否重试，非幂等命令只有在请求没有发出时才会重试
+func (p *RetryPolicy) ShouldRetry(attempt int, cmd, kind string, written bool, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	if !retryable(kind, err) {
+		return false
+	}
+	if !written {
+		return true
+	}
+	isIdempotent := p.Idempotent
+	if isIdempotent == nil {
+		isIdempotent = IsIdempotent
+	}
+	return isIdempotent(cmd)
+}
+
+// Delay returns the backoff before the next attempt, exponential with jitter
+//
+//	@param attempt number of the attempts done
+//	@return time.Duration
+//
+// 返回下次执行前的等待时间，指数增长并带有随机抖动
+func (p *RetryPolicy) Delay(attempt int) time.Duration {
+	if p.Backoff <= 0 || attempt < 1 {
+		return 0
+	}
+	d := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		j := p.Jitter
+		if j > 1 {
+			j = 1
+		}
+		d -= time.Duration(rand.Float64() * j * float64(d))
+	}
+	return d
+}
+
+// 等待到下次执行，上下文结束时返回 false
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) bool {
+	d := p.Delay(attempt)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,105 @@
+package ssdbclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/seefan/gossdb/v2/conf"
+)
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 3}
+	err := errors.New("broken pipe")
+	for _, c := range []struct {
+		attempt int
+		cmd     string
+		kind    string
+		written bool
+		want    bool
+	}{
+		{1, "get", "recv", true, true},
+		{1, "incr", "recv", true, false},
+		{1, "incr", "send", false, true},
+		{1, "qpush", "closed", false, true},
+		{1, "zincr", "timeout", true, false},
+		{1, "unknown", "recv", true, false},
+		{1, "get", "", true, false},
+		{1, "get", "panic", true, false},
+		{3, "get", "recv", true, false},
+	} {
+		if got := p.ShouldRetry(c.attempt, c.cmd, c.kind, c.written, err); got != c.want {
+			t.Error(c, got)
+		}
+	}
+	var none *RetryPolicy
+	if none.ShouldRetry(1, "get", "recv", true, err) {
+		t.Error("nil policy retried")
+	}
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	if p := NewRetryPolicy(&conf.Config{}); p != nil {
+		t.Fatal("retry is not enabled")
+	}
+	//没有经过 Default 的配置也至少重试一次
+	if p := NewRetryPolicy(&conf.Config{RetryEnabled: true}); p == nil || p.MaxAttempts != 2 {
+		t.Fatal(p)
+	}
+	if p := NewRetryPolicy(&conf.Config{RetryEnabled: true, RetryMaxAttempts: 5}); p.MaxAttempts != 5 {
+		t.Fatal(p.MaxAttempts)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := &RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for attempt, want := range []time.Duration{0, 10, 20, 40, 50, 50} {
+		if d := p.Delay(attempt); d != want*time.Millisecond {
+			t.Error(attempt, d)
+		}
+	}
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.Delay(2); d < 10*time.Millisecond || d > 20*time.Millisecond {
+			t.Fatal(d)
+		}
+	}
+}
+
+func TestSSDBClient_retry(t *testing.T) {
+	cfg := &conf.Config{
+		Host:         "127.0.0.1",
+		Port:         8888,
+		ReadTimeout:  1,
+		RetryEnabled: true,
+	}
+	c := NewSSDBClient(cfg.Default())
+	var attempts int
+	c.Observer = func(stat *Stat) {
+		attempts = stat.Attempts
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if resp, err := c.Do("sleep", 0); err != nil || len(resp) == 0 || resp[0] != ok {
+		//sleep 是测试服务的命令，真实的 ssdb 没有这个命令
+		t.Skip("the server does not support the sleep command of the test server")
+	}
+	//未发出的请求，非幂等命令也会重试
+	_ = c.Close()
+	if _, err := c.Do("incr", "retry_counter", 1); err != nil || attempts != 2 {
+		t.Fatal(err, attempts)
+	}
+	//已发出的非幂等命令不重试
+	if _, err := c.Do("sleep", 1500); err == nil || attempts != 1 {
+		t.Fatal(err, attempts)
+	}
+	c.Retry.Idempotent = func(cmd string) bool { return true }
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do("sleep", 1500); err == nil || attempts != 2 {
+		t.Fatal(err, attempts)
+	}
+}
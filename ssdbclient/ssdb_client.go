@@ -4,7 +4,13 @@
 package ssdbclient
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/seefan/goerr"
 	"github.com/seefan/gossdb/v2/conf"
@@ -24,6 +30,7 @@ const (
 // 使用配置创建一个新的SSDBClient，并不实际打开连接
 func NewSSDBClient(cfg *conf.Config) *SSDBClient {
 	return &SSDBClient{
+		id: atomic.AddUint64(&lastID, 1),
 		connection: connection{
 			host:            cfg.Host,
 			port:            cfg.Port,
@@ -32,21 +39,37 @@ func NewSSDBClient(cfg *conf.Config) *SSDBClient {
 			readBufferSize:  cfg.ReadBufferSize,
 			writeBufferSize: cfg.WriteBufferSize,
 			connectTimeout:  cfg.ConnectTimeout,
+			sockLock:        new(sync.Mutex),
 		},
-		retryEnabled: cfg.RetryEnabled,
-		password:     cfg.Password,
-		encoding:     cfg.Encoding,
+		Retry:    NewRetryPolicy(cfg),
+		password: cfg.Password,
+		encoding: cfg.Encoding,
+		logger:   cfg.Logger,
 	}
 }
 
+// 最后分配的连接 id
+var lastID uint64
+
 // SSDBClient ssdb client
 type SSDBClient struct {
 	//内嵌连接
 	connection
-	//是否重试
-	retryEnabled bool
+	//连接 id，进程内唯一
+	id uint64
+	//retry policy of the failed commands, nil to disable retry
+	//命令失败时的重试策略，为空时不重试
+	Retry *RetryPolicy
+	//最后一次执行失败时请求是否已经发出
+	written bool
+	//正在执行的命令是否被中断
+	interrupted int32
+	//连接打开的时间
+	startTime time.Time
 	//是否自动转码
 	encoding bool
+	//可选的日志
+	logger *slog.Logger
 	//whether the connection is open
 	isOpen   bool
 	password string
@@ -54,6 +77,65 @@ type SSDBClient struct {
 	//and can be modified to use a custom serialization
 	//将输入参数成[]byte，默认会转换成json格式,可以修改这个参数以便使用自定义的序列化方式
 	EncodingFunc func(v interface{}) []byte
+	//The observer is called after each command, used for metrics
+	//每个命令执行后调用，用于统计
+	Observer func(stat *Stat)
+	//最后一次错误的类型
+	errKind string
+	//the context of the commands, used for tracing
+	//执行命令的上下文，用于追踪
+	ctx context.Context
+}
+
+// Stat the statistics of a command, passed to the Observer
+//
+// 一个命令的统计信息，传递给 Observer
+type Stat struct {
+	//command and arguments
+	//命令和参数
+	Args []interface{}
+	//response
+	//返回值
+	Resp []string
+	//error
+	//错误
+	Err error
+	//error kind: closed, send, recv, timeout, panic, connect, empty if no error
+	//错误类型：closed, send, recv, timeout, panic, connect，没有错误时为空
+	ErrKind string
+	//start time
+	//开始时间
+	Start time.Time
+	//duration
+	//耗时
+	Duration time.Duration
+	//context of the client, see SetContext
+	//连接的上下文，见 SetContext
+	Ctx context.Context
+	//bytes sent, including the retry
+	//发送的字节数，包括重试
+	BytesSent int64
+	//bytes received, including the retry
+	//接收的字节数，包括重试
+	BytesReceived int64
+	//number of attempts, more than 1 if retried
+	//执行的次数，重试时大于 1
+	Attempts int
+	//connection id
+	//连接 id
+	ConnID uint64
+}
+
+// Command returns the command name
+//
+// 返回命令名称
+func (s *Stat) Command() string {
+	if len(s.Args) > 0 {
+		if cmd, ok := s.Args[0].(string); ok {
+			return cmd
+		}
+	}
+	return ""
 }
 
 // Start start socket
@@ -72,11 +154,40 @@ func (s *SSDBClient) Start() error {
 			}
 			return nil
 		}
+	} else {
+		s.connection.encodingFunc = nil
 	}
 	s.isOpen = true
+	s.startTime = time.Now()
 	return s.auth()
 }
 
+// Configure apply the connection settings of the config, it must be called when the connection is closed and takes effect on the next Start.
+// The retry policy is not changed.
+//
+//	@param cfg config
+//
+// 使用配置中的连接设置，必须在连接关闭时调用，下次 Start 时生效。不修改重试策略。
+func (s *SSDBClient) Configure(cfg *conf.Config) {
+	s.host = cfg.Host
+	s.port = cfg.Port
+	s.readTimeout = cfg.ReadTimeout
+	s.writeTimeout = cfg.WriteTimeout
+	s.readBufferSize = cfg.ReadBufferSize
+	s.writeBufferSize = cfg.WriteBufferSize
+	s.connectTimeout = cfg.ConnectTimeout
+	s.password = cfg.Password
+	s.encoding = cfg.Encoding
+	s.logger = cfg.Logger
+}
+
+// StartTime returns the time the connection was opened
+//
+// 返回连接打开的时间
+func (s *SSDBClient) StartTime() time.Time {
+	return s.startTime
+}
+
 // Close close SSDBClient
 //
 //	@return error that may occur on shutdown. Return nil if successful shutdown
@@ -85,6 +196,49 @@ func (s *SSDBClient) Close() error {
 	return s.close()
 }
 
+// ID returns the connection id, it is unique in the process
+//
+// 返回连接 id，进程内唯一
+func (s *SSDBClient) ID() uint64 {
+	return s.id
+}
+
+// SetContext set the context of the following commands, the commands are traced as children of the span in the context
+//
+//	@param ctx context, nil to reset
+//
+// 设置后续命令的上下文，命令将作为上下文中 span 的子级被追踪
+func (s *SSDBClient) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// Context returns the context of the commands
+//
+//	@return context.Context context.Background() if not set
+//
+// 返回命令的上下文
+func (s *SSDBClient) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// Interrupt abort the running command from another goroutine, the connection is closed and the command is not retried
+//
+// 在其它协程中中断正在执行的命令，连接将被关闭并且不会重试
+func (s *SSDBClient) Interrupt() {
+	atomic.StoreInt32(&s.interrupted, 1)
+	if s.sockLock == nil {
+		return
+	}
+	s.sockLock.Lock()
+	defer s.sockLock.Unlock()
+	if s.sock != nil {
+		_ = s.sock.CloseRead()
+	}
+}
+
 // IsOpen check if the connection is open
 //
 //	@return bool returns true if the connection is open
@@ -96,25 +250,77 @@ func (s *SSDBClient) IsOpen() bool {
 
 // 执行ssdb命令
 func (s *SSDBClient) do(args ...interface{}) (resp []string, err error) {
+	s.written = false
 	if !s.isOpen {
+		s.errKind = "closed"
 		return nil, goerr.String("gossdb client is closed.")
 	}
+	flushed := s.flushed()
 	defer func() {
 		if e := recover(); e != nil {
 			s.isOpen = false
+			s.errKind = "panic"
 			err = fmt.Errorf("%v", e)
 		}
+		if err != nil {
+			s.written = s.flushed() != flushed
+		}
 	}()
 	if err = s.send(args); err != nil {
 		s.isOpen = false
+		s.errKind = errKind(err, "send")
 		return nil, goerr.Errorf(err, "client send error")
 	}
 	if resp, err = s.recv(); err != nil {
 		s.isOpen = false
+		s.errKind = errKind(err, "recv")
 		return nil, goerr.Errorf(err, "client recv error")
 	}
 	return
 }
+
+// Send write a command without waiting for the response, it is used for pipelining with Recv.
+// Send and Recv can be called from two goroutines at the same time, the responses are received in the order of the commands.
+// The caller should close the connection if any error.
+//
+//	@param args the command and the arguments
+//	@return error possible error, operation successfully returned nil
+//
+// 发送命令但不等待响应，与 Recv 配合实现流水线。Send 和 Recv 可以分别在两个协程中同时调用，响应按命令的顺序接收。出错时调用者需要关闭连接。
+func (s *SSDBClient) Send(args ...interface{}) error {
+	if !s.isOpen {
+		return goerr.String("gossdb client is closed.")
+	}
+	if err := s.send(args); err != nil {
+		return goerr.Errorf(err, "client send error")
+	}
+	return nil
+}
+
+// Recv read the response of the earliest command sent by Send and not received
+//
+//	@return []string response
+//	@return error possible error, operation successfully returned nil
+//
+// 接收最早发送且还未接收的命令的响应
+func (s *SSDBClient) Recv() ([]string, error) {
+	if !s.isOpen {
+		return nil, goerr.String("gossdb client is closed.")
+	}
+	resp, err := s.recv()
+	if err != nil {
+		return nil, goerr.Errorf(err, "client recv error")
+	}
+	return resp, nil
+}
+
+// 错误的类型，超时单独区分
+func errKind(err error, kind string) string {
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return "timeout"
+	}
+	return kind
+}
 func (s *SSDBClient) auth() error {
 	if s.password == "" { //without a password, authentication is not required
 		return nil
@@ -122,6 +328,7 @@ func (s *SSDBClient) auth() error {
 	//if !s.isAuth {
 	resp, err := s.do("auth", s.password)
 	if err != nil {
+		s.log(slog.LevelError, "gossdb authentication failed", slog.String("error", err.Error()))
 		if e := s.Close(); e != nil {
 			err = goerr.Errorf(err, "client close failed")
 		}
@@ -132,6 +339,7 @@ func (s *SSDBClient) auth() error {
 		//s.isAuth = true
 		return nil
 	}
+	s.log(slog.LevelError, "gossdb authentication failed", slog.String("error", "password is wrong"))
 	return goerr.String("authentication failed,password is wrong")
 
 	//}
@@ -145,25 +353,64 @@ func (s *SSDBClient) auth() error {
 //	@return error Possible errors
 //
 // 通用调用方法，所有操作ssdb的函数最终都是调用这个函数
-func (s *SSDBClient) Do(args ...interface{}) ([]string, error) {
+func (s *SSDBClient) Do(args ...interface{}) (resp []string, err error) {
 	//if err := s.auth(); err != nil {
 	//	return nil, err
 	//}
-	resp, err := s.do(args...)
-	if err != nil {
+	attempts := 1
+	atomic.StoreInt32(&s.interrupted, 0)
+	if s.Observer != nil {
+		s.errKind = ""
+		s.sent, s.received = 0, 0
+		start := time.Now()
+		defer func() {
+			stat := &Stat{
+				Args:          args,
+				Resp:          resp,
+				Err:           err,
+				Start:         start,
+				Duration:      time.Since(start),
+				Ctx:           s.Context(),
+				BytesSent:     s.sent,
+				BytesReceived: s.received,
+				Attempts:      attempts,
+				ConnID:        s.id,
+			}
+			if err != nil {
+				stat.ErrKind = s.errKind
+			}
+			s.Observer(stat)
+		}()
+	}
+	resp, err = s.do(args...)
+	for err != nil {
 		if e := s.Close(); e != nil {
 			err = goerr.Errorf(err, "client close failed")
 		}
-		if s.retryEnabled { //如果允许重试，就重新打开一次连接
-			if err = s.Start(); err == nil {
-				resp, err = s.do(args...)
-				if err != nil {
-					if e := s.Close(); e != nil {
-						err = goerr.Errorf(err, "client close failed")
-					}
-				}
+		//按重试策略重新打开连接再执行，非幂等命令只在请求没有发出时重试
+		cmd := (&Stat{Args: args}).Command()
+		if atomic.LoadInt32(&s.interrupted) == 1 {
+			s.errKind = "interrupted"
+			break
+		}
+		if !s.Retry.ShouldRetry(attempts, cmd, s.errKind, s.written, err) {
+			if attempts > 1 {
+				s.log(slog.LevelError, "gossdb command retry failed", slog.String("command", cmd), slog.Int("attempts", attempts), slog.String("error", err.Error()))
 			}
+			break
+		}
+		s.log(slog.LevelWarn, "gossdb command failed, retrying", slog.String("command", cmd), slog.Int("attempt", attempts), slog.String("error", err.Error()))
+		if !s.Retry.wait(s.Context(), attempts) {
+			break
+		}
+		attempts++
+		if err = s.Start(); err != nil {
+			s.errKind = "connect"
+			s.written = false
+			s.log(slog.LevelError, "gossdb reconnect failed", slog.String("command", cmd), slog.String("error", err.Error()))
+			continue
 		}
+		resp, err = s.do(args...)
 	}
 	return resp, err
 }
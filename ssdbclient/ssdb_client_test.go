@@ -1,8 +1,13 @@
 package ssdbclient
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"log/slog"
+	"net"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -336,3 +341,143 @@ func BenchmarkConnectors_conv(b *testing.B) {
 		}
 	})
 }
+func TestSSDBClient_log(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := &conf.Config{
+		Host:         "127.0.0.1",
+		Port:         8888,
+		RetryEnabled: true,
+		Logger:       slog.New(slog.NewTextHandler(&buf, nil)),
+	}
+	c := NewSSDBClient(cfg.Default())
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Close()
+	if _, err := c.Do("get", "a"); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Close()
+	out := buf.String()
+	for _, s := range []string{"gossdb command failed, retrying", "command=get", "conn_id=" + strconv.FormatUint(c.ID(), 10)} {
+		if !strings.Contains(out, s) {
+			t.Fatal(s, out)
+		}
+	}
+}
+func TestSSDBClient_pipeline(t *testing.T) {
+	cfg := &conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	}
+	c := NewSSDBClient(cfg.Default())
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	//多个响应可能在一次读取中到达，多读的部分留给下一次 Recv
+	keys := []string{"p1", "p2", "p3", "p4"}
+	for _, k := range keys {
+		if err := c.Send("set", k, k+"v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, k := range keys {
+		if err := c.Send("get", k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range keys {
+		if resp, err := c.Recv(); err != nil || resp[0] != ok {
+			t.Fatal(resp, err)
+		}
+	}
+	for _, k := range keys {
+		if resp, err := c.Recv(); err != nil || len(resp) != 2 || resp[1] != k+"v" {
+			t.Fatal(resp, err)
+		}
+	}
+	if v, err := c.Do("get", "p1"); err != nil || v[1] != "p1v" {
+		t.Fatal(v, err)
+	}
+}
+
+func TestSSDBClient_zero(t *testing.T) {
+	c := &SSDBClient{}
+	c.host, c.port, c.readTimeout, c.writeTimeout = "127.0.0.1", 8888, 1, 1
+	c.readBufferSize, c.writeBufferSize, c.connectTimeout = 8, 8, 1
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Do("version"); err != nil {
+		t.Fatal(err)
+	}
+	c.Interrupt()
+}
+
+func TestSSDBClient_interrupt(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for first := true; ; first = false {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(first bool) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					//只读取到请求的结束行
+					for {
+						line, err := r.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if line == "\n" {
+							break
+						}
+					}
+					if first {
+						//第一个连接只返回一半的响应
+						_, _ = conn.Write([]byte("2\nok\n10\nabc"))
+						continue
+					}
+					if _, err := conn.Write([]byte("2\nok\n3\nabc\n\n")); err != nil {
+						return
+					}
+				}
+			}(first)
+		}
+	}()
+	cfg := &conf.Config{
+		Host: "127.0.0.1",
+		Port: l.Addr().(*net.TCPAddr).Port,
+	}
+	c := NewSSDBClient(cfg.Default())
+	if err = c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.Interrupt()
+	}()
+	if _, err = c.Do("get", "a"); err == nil {
+		t.Fatal("the interrupted read returns no error")
+	}
+	//中断后重新打开的连接不受之前解析到一半的响应影响
+	_ = c.Close()
+	if err = c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 0; i < 3; i++ {
+		if resp, err := c.Do("get", "a"); err != nil || len(resp) != 2 || resp[1] != "abc" {
+			t.Fatal(resp, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package timeseries
+
+import (
+	"math"
+	"time"
+)
+
+// Aggregation downsampling aggregation
+//
+// 降采样的聚合方式
+type Aggregation int
+
+const (
+	//Avg average of the bucket
+	//平均值
+	Avg Aggregation = iota
+	//Min minimum of the bucket
+	//最小值
+	Min
+	//Max maximum of the bucket
+	//最大值
+	Max
+	//Sum sum of the bucket
+	//合计
+	Sum
+	//Count number of points in the bucket
+	//点的个数
+	Count
+)
+
+// String returns the aggregation name
+func (a Aggregation) String() string {
+	switch a {
+	case Avg:
+		return "avg"
+	case Min:
+		return "min"
+	case Max:
+		return "max"
+	case Sum:
+		return "sum"
+	case Count:
+		return "count"
+	}
+	return "unknown"
+}
+
+// Downsample aggregate the points into buckets
+//
+//	@param points points ordered by time
+//	@param bucket bucket size, the bucket start time is aligned to a multiple of bucket
+//	@param agg aggregation
+//	@return []Point one point per non-empty bucket, the time is the bucket start time
+//
+// 按 bucket 对点进行聚合，bucket 的开始时间按 bucket 的整数倍对齐，空的 bucket 不返回
+func Downsample(points []Point, bucket time.Duration, agg Aggregation) []Point {
+	if bucket <= 0 {
+		return points
+	}
+	size := int64(bucket / time.Millisecond)
+	if size < 1 {
+		size = 1
+	}
+	var re []Point
+	var start, count int64
+	var value float64
+	for i, p := range points {
+		ms := toMillisecond(p.Time)
+		bs := ms - mod(ms, size)
+		if i == 0 || bs != start {
+			if i > 0 {
+				re = append(re, Point{Time: fromMillisecond(start), Value: result(agg, value, count)})
+			}
+			start, count, value = bs, 0, initial(agg)
+		}
+		count++
+		value = accumulate(agg, value, p.Value)
+	}
+	if count > 0 {
+		re = append(re, Point{Time: fromMillisecond(start), Value: result(agg, value, count)})
+	}
+	return re
+}
+
+// 取模，负数时间也按向下对齐
+func mod(v, size int64) int64 {
+	m := v % size
+	if m < 0 {
+		m += size
+	}
+	return m
+}
+
+func initial(agg Aggregation) float64 {
+	switch agg {
+	case Min:
+		return math.Inf(1)
+	case Max:
+		return math.Inf(-1)
+	}
+	return 0
+}
+
+func accumulate(agg Aggregation, acc, v float64) float64 {
+	switch agg {
+	case Min:
+		return math.Min(acc, v)
+	case Max:
+		return math.Max(acc, v)
+	case Count:
+		return acc + 1
+	}
+	return acc + v
+}
+
+func result(agg Aggregation, acc float64, count int64) float64 {
+	if agg == Avg {
+		return acc / float64(count)
+	}
+	return acc
+}
@@ -0,0 +1,248 @@
+// Package timeseries time series storage on top of ssdb zset and hashmap
+//
+// 时间序列存储，时间戳保存在 zset 中（score 为毫秒时间戳），数值保存在同名的 hashmap 中，以时间戳为 key
+package timeseries
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/seefan/goerr"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+// 每次从 zset 中扫描的数量
+const pageSize = 1000
+
+// Point a point of the series
+//
+// 时间序列中的一个点
+type Point struct {
+	//point time, millisecond precision
+	//时间，精确到毫秒
+	Time time.Time
+	//value
+	//数值
+	Value float64
+}
+
+// Series time series
+//
+// 时间序列，同一个毫秒内的点会相互覆盖
+type Series struct {
+	//series name, used as zset and hashmap name
+	//序列名称，同时作为 zset 和 hashmap 的名称
+	name string
+	//连接池
+	pool *pool.Connectors
+}
+
+// New create a series
+//
+//	@param p connection pool
+//	@param name series name
+//	@return *Series
+//
+// 使用连接池创建一个时间序列
+func New(p *pool.Connectors, name string) *Series {
+	return &Series{
+		name: name,
+		pool: p,
+	}
+}
+
+// Name returns the series name
+func (s *Series) Name() string {
+	return s.name
+}
+
+// 取一个连接，多个命令共用，使用完后需要关闭
+func (s *Series) client() (*pool.Client, error) {
+	c, err := s.pool.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	c.AutoClose = false
+	return c, nil
+}
+
+// Append append points to the series
+//
+//	@param points the points
+//	@return error possible error, operation successfully returned nil
+//
+// 添加点，先写 hashmap 再写 zset，保证扫描到的时间戳都有对应的值
+func (s *Series) Append(points ...Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	c, err := s.client()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	values := make(map[string]interface{}, len(points))
+	scores := make(map[string]int64, len(points))
+	for _, p := range points {
+		ms := toMillisecond(p.Time)
+		key := strconv.FormatInt(ms, 10)
+		values[key] = p.Value
+		scores[key] = ms
+	}
+	if err = c.MultiHSet(s.name, values); err != nil {
+		return goerr.Errorf(err, "append %s error", s.name)
+	}
+	if err = c.MultiZSet(s.name, scores); err != nil {
+		return goerr.Errorf(err, "append %s error", s.name)
+	}
+	return nil
+}
+
+// Add append a value at time t
+//
+//	@param t time
+//	@param value value
+//	@return error possible error, operation successfully returned nil
+//
+// 在指定时间添加一个值
+func (s *Series) Add(t time.Time, value float64) error {
+	return s.Append(Point{Time: t, Value: value})
+}
+
+// Range returns the points in [from, to], ordered by time
+//
+//	@param from start time, included
+//	@param to end time, included
+//	@return []Point
+//	@return error possible error, operation successfully returned nil
+//
+// 返回区间 [from, to] 内的点，按时间排序
+func (s *Series) Range(from, to time.Time) ([]Point, error) {
+	c, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	var re []Point
+	err = s.scan(c, toMillisecond(from), toMillisecond(to), func(keys []string, scores []int64) error {
+		values, err := c.MultiHGet(s.name, keys...)
+		if err != nil {
+			return err
+		}
+		for i, k := range keys {
+			if v, ok := values[k]; ok {
+				re = append(re, Point{Time: fromMillisecond(scores[i]), Value: v.Float64()})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, goerr.Errorf(err, "range %s error", s.name)
+	}
+	return re, nil
+}
+
+// Aggregate returns the points in [from, to] downsampled into buckets
+//
+//	@param from start time, included
+//	@param to end time, included
+//	@param bucket bucket size
+//	@param agg aggregation
+//	@return []Point
+//	@return error possible error, operation successfully returned nil
+//
+// 返回区间 [from, to] 内按 bucket 聚合后的点，聚合在客户端完成
+func (s *Series) Aggregate(from, to time.Time, bucket time.Duration, agg Aggregation) ([]Point, error) {
+	points, err := s.Range(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return Downsample(points, bucket, agg), nil
+}
+
+// Trim removes the points before the specified time
+//
+//	@param before the points before this time will be removed, excluded
+//	@return int64 the number of removed points
+//	@return error possible error, operation successfully returned nil
+//
+// 删除指定时间之前的点。先删除 hashmap 中的值，再用 ZRemRangeByScore 删除时间戳
+func (s *Series) Trim(before time.Time) (int64, error) {
+	c, err := s.client()
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	end := toMillisecond(before) - 1
+	var size int64
+	err = s.scan(c, math.MinInt64, end, func(keys []string, scores []int64) error {
+		size += int64(len(keys))
+		return c.MultiHDel(s.name, keys...)
+	})
+	if err == nil && size > 0 {
+		err = c.ZRemRangeByScore(s.name, math.MinInt64, end)
+	}
+	if err != nil {
+		return 0, goerr.Errorf(err, "trim %s error", s.name)
+	}
+	return size, nil
+}
+
+// Retain keeps the points within the retention period
+//
+//	@param retention retention period
+//	@return int64 the number of removed points
+//	@return error possible error, operation successfully returned nil
+//
+// 保留最近一段时间内的点，其它的删除
+func (s *Series) Retain(retention time.Duration) (int64, error) {
+	return s.Trim(time.Now().Add(-retention))
+}
+
+// Clear removes all points
+//
+//	@return error possible error, operation successfully returned nil
+//
+// 删除所有的点
+func (s *Series) Clear() error {
+	c, err := s.client()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err = c.HClear(s.name); err != nil {
+		return err
+	}
+	return c.ZClear(s.name)
+}
+
+// 分页扫描 zset 中 score 处于 [start, end] 的时间戳
+func (s *Series) scan(c *pool.Client, start, end int64, fn func(keys []string, scores []int64) error) error {
+	keyStart := ""
+	for {
+		keys, scores, err := c.ZScan(s.name, keyStart, start, end, pageSize)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		if err = fn(keys, scores); err != nil {
+			return err
+		}
+		if len(keys) < pageSize {
+			return nil
+		}
+		keyStart = keys[len(keys)-1]
+		start = scores[len(scores)-1]
+	}
+}
+
+func toMillisecond(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMillisecond(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
@@ -0,0 +1,85 @@
+package timeseries
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seefan/gossdb/v2/conf"
+	"github.com/seefan/gossdb/v2/pool"
+)
+
+func TestDownsample(t *testing.T) {
+	base := time.Unix(1000, 0)
+	points := []Point{
+		{Time: base, Value: 1},
+		{Time: base.Add(time.Second), Value: 3},
+		{Time: base.Add(time.Minute), Value: 5},
+		{Time: base.Add(time.Minute + time.Second), Value: 2},
+	}
+	tests := []struct {
+		agg  Aggregation
+		want []float64
+	}{
+		{Avg, []float64{2, 3.5}},
+		{Min, []float64{1, 2}},
+		{Max, []float64{3, 5}},
+		{Sum, []float64{4, 7}},
+		{Count, []float64{2, 2}},
+	}
+	for _, tt := range tests {
+		re := Downsample(points, time.Minute, tt.agg)
+		if len(re) != len(tt.want) {
+			t.Fatalf("%s: got %d buckets, want %d", tt.agg, len(re), len(tt.want))
+		}
+		for i, p := range re {
+			if p.Value != tt.want[i] {
+				t.Errorf("%s: bucket %d got %v, want %v", tt.agg, i, p.Value, tt.want[i])
+			}
+			if p.Time.Unix()%60 != 0 {
+				t.Errorf("%s: bucket %d is not aligned, %v", tt.agg, i, p.Time)
+			}
+		}
+	}
+}
+
+func TestSeries(t *testing.T) {
+	p := pool.NewConnectors(&conf.Config{
+		Host: "127.0.0.1",
+		Port: 8888,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	s := New(p, "ts:test")
+	if err := s.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Unix(1600000000, 0)
+	for i := 0; i < 10; i++ {
+		if err := s.Add(base.Add(time.Duration(i)*time.Second), float64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	points, err := s.Range(base.Add(2*time.Second), base.Add(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 4 || points[0].Value != 2 || points[3].Value != 5 {
+		t.Errorf("range error %v", points)
+	}
+	size, err := s.Trim(base.Add(5 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 5 {
+		t.Errorf("trim size is %d", size)
+	}
+	agg, err := s.Aggregate(base, base.Add(time.Minute), 5*time.Second, Sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agg) != 1 || agg[0].Value != 35 {
+		t.Errorf("aggregate error %v", agg)
+	}
+}